@@ -442,8 +442,9 @@ resource "terraform_data" "put_vectordb_model" {
     command     = "${path.module}/scripts/put_vectordb_model.sh ${local.binaries_path}"
     interpreter = ["/bin/bash", "-c"]
     environment = {
-      ES               = local.es_url
-      ELSER_MODEL_TYPE = var.elser_model_type
+      ES                    = local.es_url
+      ES_CERTIFICATE_BASE64 = data.ibm_database_connection.database_connection[0].https[0].certificate[0].certificate_base64
+      ELSER_MODEL_TYPE      = var.elser_model_type
     }
   }
 }
@@ -455,11 +456,12 @@ resource "terraform_data" "start_vectordb_model" {
     file_changed = md5(var.elser_model_type)
   }
   provisioner "local-exec" {
-    command     = "${path.module}/scripts/start_vectordb_model.sh"
+    command     = "${path.module}/scripts/start_vectordb_model.sh ${local.binaries_path}"
     interpreter = ["/bin/bash", "-c"]
     environment = {
-      ES               = local.es_url
-      ELSER_MODEL_TYPE = var.elser_model_type
+      ES                    = local.es_url
+      ES_CERTIFICATE_BASE64 = data.ibm_database_connection.database_connection[0].https[0].certificate[0].certificate_base64
+      ELSER_MODEL_TYPE      = var.elser_model_type
     }
   }
 }
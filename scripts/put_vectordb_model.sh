@@ -5,16 +5,23 @@ set -e
 export PATH=$PATH:${1:-"/tmp"}
 
 INSTALL_NEW_MODEL=true
+# Each request is retried four times, first after twice this delay in seconds, and the delay doubles every attempt.
+RETRY_DELAY=${ELSER_RETRY_DELAY:-2}
+
+# TLS is verified with the CA certificate of the instance.
+CA_CERT=$(mktemp)
+trap 'rm -f "$CA_CERT"' EXIT
+echo "$ES_CERTIFICATE_BASE64" | base64 -d > "$CA_CERT"
 
 # get trained models from elasticsearch
 echo "Pulling trained models from elasticsearch."
-sleep=2
+sleep=$RETRY_DELAY
 for i in $(seq 1 4); do
 
     sleep=$((sleep*2))
 
     sleep $sleep
-    result=$(curl -s -w "%{http_code}" -kX GET "$ES/_ml/trained_models?pretty")
+    result=$(curl -s -w "%{http_code}" --cacert "$CA_CERT" -X GET "$ES/_ml/trained_models?pretty")
 
     http_code=$(tail -n1 <<< "$result")
     content=$(sed '$ d' <<< "$result")
@@ -49,12 +56,12 @@ do
     if [ "$ELSER_MODEL_TYPE" != "$model" ]; then
         # deleting trained model
         echo "Deleting trained model '$model' from elasticsearch"
-        sleep=2
+        sleep=$RETRY_DELAY
         for i in $(seq 1 4); do
           sleep=$((sleep*2))
 
           sleep $sleep
-          result=$(curl -s -w "%{http_code}" -kX DELETE "$ES/_ml/trained_models/$model?pretty")
+          result=$(curl -s -w "%{http_code}" --cacert "$CA_CERT" -X DELETE "$ES/_ml/trained_models/$model?pretty")
           http_code=$(tail -n1 <<< "$result")
           content=$(sed '$ d' <<< "$result")
           # Check the result
@@ -77,14 +84,14 @@ done
 
 # deploy a new trained model using retry
 if [ "$INSTALL_NEW_MODEL" = true ] ; then
-    sleep=2
+    sleep=$RETRY_DELAY
     echo "Deploying the new trained model '$ELSER_MODEL_TYPE' to elasticsearch ..."
     for i in $(seq 1 4); do
         sleep=$((sleep*2))
 
         sleep $sleep
         # learn more https://www.elastic.co/docs/api/doc/elasticsearch-serverless/operation/operation-ml-put-trained-model#operation-ml-put-trained-model-wait_for_completion
-        response=$(curl -s -w "%{http_code}" -kX PUT "$ES/_ml/trained_models/$ELSER_MODEL_TYPE?wait_for_completion=true&pretty" -H 'Content-Type: application/json' -d'
+        response=$(curl -s -w "%{http_code}" --cacert "$CA_CERT" -X PUT "$ES/_ml/trained_models/$ELSER_MODEL_TYPE?wait_for_completion=true&pretty" -H 'Content-Type: application/json' -d'
         {
             "input": {
             "field_names": ["text_field"]
@@ -1,26 +1,70 @@
 #!/bin/bash
 set -e
 
-#Function to start the vectorDB model
-Start_model() {
+# The binaries downloaded by the install-binaries script are located in the /tmp directory.
+export PATH=$PATH:${1:-"/tmp"}
 
-# It takes few minute for the model to finish installing before we can start trained model deployment, therefore we sleep for 180 seconds (3m).
-sleep 180
-# Learn more https://www.elastic.co/guide/en/elasticsearch/reference/current/start-trained-model-deployment.html
-response=$(curl -s -w "%{http_code}" -kX POST "$ES/_ml/trained_models/$ELSER_MODEL_TYPE/deployment/_start?wait_for=started&timeout=3m&deployment_id=for_search&pretty")
+# How long to wait, in seconds, for the model to be fully defined and for its deployment to be started.
+TIMEOUT=${ELSER_START_TIMEOUT:-900}
+POLL_INTERVAL=${ELSER_POLL_INTERVAL:-10}
+DEPLOYMENT_ID="for_search"
+DEADLINE=$((SECONDS + TIMEOUT))
 
-http_code=$(tail -n1 <<< "$response")
-content=$(sed '$ d' <<< "$response")
+# TLS is verified with the CA certificate of the instance.
+CA_CERT=$(mktemp)
+trap 'rm -f "$CA_CERT"' EXIT
+echo "$ES_CERTIFICATE_BASE64" | base64 -d > "$CA_CERT"
 
-# Check the HTTP status code
-if [ "$http_code" -eq 200 ] || [ "$http_code" -eq 201 ]; then
-  echo "Request sent successfully."
-else
-  echo "Failed to start the vectorDB model. HTTP status code: $http_code"
-  echo "Response: $content"
-  exit 1
-fi
+# Print "<state>/<allocation state>" of the deployment, "none" if the model has no such deployment, or nothing if the
+# stats cannot be read.
+Deployment_state() {
+  curl -sf --cacert "$CA_CERT" -X GET "$ES/_ml/trained_models/$ELSER_MODEL_TYPE/_stats" |
+    jq -r --arg id "$DEPLOYMENT_ID" '[.trained_model_stats[]?.deployment_stats | select(.deployment_id? == $id) | "\(.state)/\(.allocation_status.state)"] | first // "none"'
+}
 
+Wait_or_fail() {
+  if [ "$SECONDS" -ge "$DEADLINE" ]; then
+    echo "$1 after ${TIMEOUT} seconds."
+    exit 1
+  fi
+  sleep "$POLL_INTERVAL"
 }
 
-Start_model
+# It takes a few minutes after the model is installed before its deployment can be started.
+echo "Waiting for the trained model '$ELSER_MODEL_TYPE' to be fully defined."
+until [ "$(curl -s --cacert "$CA_CERT" -X GET "$ES/_ml/trained_models/$ELSER_MODEL_TYPE?include=definition_status" | jq -r '.trained_model_configs[0].fully_defined // false')" = "true" ]; do
+  Wait_or_fail "The trained model '$ELSER_MODEL_TYPE' is not fully defined"
+done
+
+until state=$(Deployment_state) && [ -n "$state" ]; do
+  Wait_or_fail "Failed to read the deployments of the trained model '$ELSER_MODEL_TYPE'"
+done
+if [ "$state" = "none" ]; then
+  # Learn more https://www.elastic.co/guide/en/elasticsearch/reference/current/start-trained-model-deployment.html
+  response=$(curl -s -w "%{http_code}" --cacert "$CA_CERT" -X POST "$ES/_ml/trained_models/$ELSER_MODEL_TYPE/deployment/_start?deployment_id=$DEPLOYMENT_ID&wait_for=starting&pretty")
+
+  http_code=$(tail -n1 <<< "$response")
+  content=$(sed '$ d' <<< "$response")
+
+  # Check the HTTP status code, 409 means that the deployment was started in the meantime
+  if [ "$http_code" -eq 200 ] || [ "$http_code" -eq 201 ]; then
+    echo "Starting the deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE'."
+  elif [ "$http_code" -eq 409 ]; then
+    echo "The deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE' already exists."
+  else
+    echo "Failed to start the vectorDB model. HTTP status code: $http_code"
+    echo "Response: $content"
+    exit 1
+  fi
+else
+  echo "The deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE' already exists: $state."
+fi
+
+until state=$(Deployment_state) && { [ "$state" = "started/started" ] || [ "$state" = "started/fully_allocated" ]; }; do
+  if [[ "$state" == failed/* ]]; then
+    echo "The deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE' failed."
+    exit 1
+  fi
+  Wait_or_fail "The deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE' is not started: $state"
+done
+echo "The deployment '$DEPLOYMENT_ID' of the trained model '$ELSER_MODEL_TYPE' is started."
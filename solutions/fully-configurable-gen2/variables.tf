@@ -120,10 +120,20 @@ variable "service_credential_names" {
   description = "A list of service credential resource keys to be created for the Elasticsearch instance. [Learn more](https://github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/blob/main/solutions/fully-configurable-gen2/DA-types.md#svc-credential-name)"
   type = list(object({
     name     = string
-    role     = optional(string, "Viewer")
+    role     = optional(string, "Writer")
     endpoint = optional(string, "private")
   }))
   default = []
+
+  validation {
+    condition     = alltrue([for credential in var.service_credential_names : contains(["Manager", "Writer"], credential.role)])
+    error_message = "`service_credential_names` role must be one of the following: `Manager` or `Writer` for Gen2 instances."
+  }
+
+  validation {
+    condition     = alltrue([for credential in var.service_credential_names : credential.endpoint == "private"])
+    error_message = "`service_credential_names` endpoint must be `private`, the only service endpoint supported by Gen2 instances."
+  }
 }
 
 variable "resource_tags" {
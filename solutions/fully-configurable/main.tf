@@ -323,6 +323,7 @@ module "elasticsearch" {
   delete_timeout                    = var.delete_timeout
   enable_elser_model                = var.enable_elser_model
   elser_model_type                  = var.elser_model_type
+  install_required_binaries         = var.install_required_binaries
   cbr_rules                         = var.cbr_rules
 }
 
@@ -0,0 +1,181 @@
+// Package autoscaling models the `auto_scaling` input of the module and the documented limits of
+// `rate_limit_mb_per_member`, which despite its name is expressed in `rate_units`.
+package autoscaling
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Units of `rate_limit_mb_per_member`. Sizes use 1024 MB per GB and 1024 GB per TB.
+const (
+	MB = "mb"
+	GB = "gb"
+	TB = "tb"
+)
+
+// Units lists the supported `rate_units` values.
+var Units = []string{MB, GB, TB}
+
+var mbPerUnit = map[string]float64{
+	MB: 1,
+	GB: 1024,
+	TB: 1024 * 1024,
+}
+
+// Documented limits of the rate limit per member, in GB
+const (
+	DiskMinGB   = 5
+	DiskMaxGB   = 4096
+	MemoryMinGB = 4
+	MemoryMaxGB = 112
+)
+
+// The module checks limits in TB against the documented limits rounded to three decimals, so they accept slightly
+// different amounts than in MB and GB: 0.005 TB is more than 5 GB and 0.109 TB is less than 112 GB.
+const (
+	DiskMinTB   = 0.005
+	DiskMaxTB   = 4
+	MemoryMinTB = 0.004
+	MemoryMaxTB = 0.109
+)
+
+// Disk is the `auto_scaling.disk` object. The zero value of a field is not its default, use Default.
+type Disk struct {
+	CapacityEnabled          bool
+	FreeSpaceLessThanPercent float64
+	IOAbovePercent           float64
+	IOEnabled                bool
+	IOOverPeriod             string
+	RateIncreasePercent      float64
+	RateLimitMBPerMember     float64
+	RatePeriodSeconds        float64
+	RateUnits                string
+}
+
+// Memory is the `auto_scaling.memory` object.
+type Memory struct {
+	IOAbovePercent       float64
+	IOEnabled            bool
+	IOOverPeriod         string
+	RateIncreasePercent  float64
+	RateLimitMBPerMember float64
+	RatePeriodSeconds    float64
+	RateUnits            string
+}
+
+// Config is the `auto_scaling` input.
+type Config struct {
+	Disk   Disk
+	Memory Memory
+}
+
+// Default returns the value Terraform uses for `auto_scaling = { disk = {}, memory = {} }`, from the optional
+// attribute defaults in variables.tf.
+func Default() Config {
+	return Config{
+		Disk: Disk{
+			FreeSpaceLessThanPercent: 10,
+			IOAbovePercent:           90,
+			IOOverPeriod:             "15m",
+			RateIncreasePercent:      10,
+			RateLimitMBPerMember:     3670016,
+			RatePeriodSeconds:        900,
+			RateUnits:                MB,
+		},
+		Memory: Memory{
+			IOAbovePercent:       90,
+			IOOverPeriod:         "15m",
+			RateIncreasePercent:  10,
+			RateLimitMBPerMember: 114688,
+			RatePeriodSeconds:    900,
+			RateUnits:            MB,
+		},
+	}
+}
+
+// ToMB converts a rate limit in units to MB.
+func ToMB(value float64, units string) (float64, error) {
+	factor, ok := mbPerUnit[units]
+	if !ok {
+		return 0, fmt.Errorf("rate_units %q must be one of: %s", units, strings.Join(Units, ", "))
+	}
+	return value * factor, nil
+}
+
+// Limits returns the lowest and highest rate limit per member that the module accepts for "disk" or "memory" in
+// units.
+func Limits(resource string, units string) (float64, float64, error) {
+	if units == TB {
+		if resource == "disk" {
+			return DiskMinTB, DiskMaxTB, nil
+		}
+		return MemoryMinTB, MemoryMaxTB, nil
+	}
+	factor, ok := mbPerUnit[units]
+	if !ok {
+		return 0, 0, fmt.Errorf("rate_units %q must be one of: %s", units, strings.Join(Units, ", "))
+	}
+	if resource == "disk" {
+		return DiskMinGB * mbPerUnit[GB] / factor, DiskMaxGB * mbPerUnit[GB] / factor, nil
+	}
+	return MemoryMinGB * mbPerUnit[GB] / factor, MemoryMaxGB * mbPerUnit[GB] / factor, nil
+}
+
+func checkLimit(resource string, value float64, units string) error {
+	lowest, highest, err := Limits(resource, units)
+	if err != nil {
+		return fmt.Errorf("%s: %w", resource, err)
+	}
+	if value < lowest || value > highest {
+		return fmt.Errorf("%s: rate_limit_mb_per_member %s %s is not between %s and %s %s",
+			resource, format(value), units, format(lowest), format(highest), units)
+	}
+	return nil
+}
+
+// Validate checks the rate limits against the limits of the module. All violations are returned.
+func (c Config) Validate() error {
+	return errors.Join(
+		checkLimit("disk", c.Disk.RateLimitMBPerMember, c.Disk.RateUnits),
+		checkLimit("memory", c.Memory.RateLimitMBPerMember, c.Memory.RateUnits),
+	)
+}
+
+// HCL renders the configuration as an HCL object expression, as it would be passed in a tfvars file.
+func (c Config) HCL() string {
+	d, m := c.Disk, c.Memory
+	return fmt.Sprintf(`{
+  disk = {
+    capacity_enabled             = %t
+    free_space_less_than_percent = %s
+    io_above_percent             = %s
+    io_enabled                   = %t
+    io_over_period               = %q
+    rate_increase_percent        = %s
+    rate_limit_mb_per_member     = %s
+    rate_period_seconds          = %s
+    rate_units                   = %q
+  }
+  memory = {
+    io_above_percent         = %s
+    io_enabled               = %t
+    io_over_period           = %q
+    rate_increase_percent    = %s
+    rate_limit_mb_per_member = %s
+    rate_period_seconds      = %s
+    rate_units               = %q
+  }
+}`,
+		d.CapacityEnabled, format(d.FreeSpaceLessThanPercent), format(d.IOAbovePercent), d.IOEnabled, d.IOOverPeriod,
+		format(d.RateIncreasePercent), format(d.RateLimitMBPerMember), format(d.RatePeriodSeconds), d.RateUnits,
+		format(m.IOAbovePercent), m.IOEnabled, m.IOOverPeriod, format(m.RateIncreasePercent),
+		format(m.RateLimitMBPerMember), format(m.RatePeriodSeconds), m.RateUnits)
+}
+
+// format writes a number exactly, without an exponent, so HCL parses the same value back
+func format(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
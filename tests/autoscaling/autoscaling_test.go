@@ -0,0 +1,105 @@
+package autoscaling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMB(t *testing.T) {
+	for _, tc := range []struct {
+		value float64
+		units string
+		want  float64
+	}{
+		{5120, MB, 5120},
+		{5, GB, 5120},
+		{4, TB, 4194304},
+		{112.0 / 1024, TB, 114688},
+	} {
+		got, err := ToMB(tc.value, tc.units)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "%v %s", tc.value, tc.units)
+	}
+
+	_, err := ToMB(1, "MB")
+	assert.ErrorContains(t, err, `rate_units "MB" must be one of: mb, gb, tb`)
+}
+
+func TestLimits(t *testing.T) {
+	for _, tc := range []struct {
+		resource, units string
+		lowest, highest float64
+	}{
+		{"disk", MB, 5120, 4194304},
+		{"disk", GB, 5, 4096},
+		{"disk", TB, 0.005, 4},
+		{"memory", MB, 4096, 114688},
+		{"memory", GB, 4, 112},
+		{"memory", TB, 0.004, 0.109},
+	} {
+		lowest, highest, err := Limits(tc.resource, tc.units)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{tc.lowest, tc.highest}, []float64{lowest, highest}, "%s in %s", tc.resource, tc.units)
+	}
+
+	_, _, err := Limits("disk", "pb")
+	assert.ErrorContains(t, err, `rate_units "pb" must be one of: mb, gb, tb`)
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	assert.NoError(t, Default().Validate())
+}
+
+type limit struct {
+	value float64
+	units string
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		disk    limit
+		memory  limit
+		wantErr []string
+	}{
+		{name: "lower bounds in mb", disk: limit{5120.0, MB}, memory: limit{4096.0, MB}},
+		{name: "upper bounds in gb", disk: limit{4096.0, GB}, memory: limit{112.0, GB}},
+		{name: "rounded bounds in tb", disk: limit{0.005, TB}, memory: limit{0.109, TB}},
+		{name: "5 GB is below the rounded disk minimum in tb", disk: limit{5.0 / 1024, TB}, memory: limit{4.0, GB}, wantErr: []string{"disk: rate_limit_mb_per_member 0.0048828125 tb is not between 0.005 and 4 tb"}},
+		{name: "disk too small", disk: limit{5119.0, MB}, memory: limit{4.0, GB}, wantErr: []string{"disk: rate_limit_mb_per_member 5119 mb is not between 5120 and 4194304 mb"}},
+		{
+			name:    "both too large",
+			disk:    limit{4.001, TB},
+			memory:  limit{112.0 / 1024, TB},
+			wantErr: []string{"disk: rate_limit_mb_per_member 4.001 tb", "memory: rate_limit_mb_per_member 0.109375 tb is not between 0.004 and 0.109 tb"},
+		},
+		{name: "unknown units", disk: limit{5.0, "pb"}, memory: limit{4.0, GB}, wantErr: []string{`disk: rate_units "pb"`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Default()
+			c.Disk.RateLimitMBPerMember, c.Disk.RateUnits = tc.disk.value, tc.disk.units
+			c.Memory.RateLimitMBPerMember, c.Memory.RateUnits = tc.memory.value, tc.memory.units
+			err := c.Validate()
+			if len(tc.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, want := range tc.wantErr {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestHCL(t *testing.T) {
+	c := Default()
+	c.Memory.RateLimitMBPerMember = 0.000001
+	hcl := c.HCL()
+	assert.Contains(t, hcl, "rate_limit_mb_per_member     = 3670016\n")
+	assert.Contains(t, hcl, "rate_limit_mb_per_member = 0.000001\n")
+	assert.Contains(t, hcl, `io_over_period               = "15m"`)
+}
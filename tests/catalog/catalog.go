@@ -0,0 +1,260 @@
+// Package catalog reads ibm_catalog.json and checks each flavor against the variables of the deployable
+// architecture in its working directory, catching mistakes that would otherwise only fail catalog onboarding. It also
+// renders the validation values templates that onboarding deploys each flavor with.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// Manifest is the part of ibm_catalog.json that describes the flavors.
+type Manifest struct {
+	Products []Product `json:"products"`
+}
+
+// Product is a catalog offering.
+type Product struct {
+	Name    string   `json:"name"`
+	Flavors []Flavor `json:"flavors"`
+}
+
+// Flavor is a deployable architecture of a product.
+type Flavor struct {
+	Name               string       `json:"name"`
+	WorkingDirectory   string       `json:"working_directory"`
+	Configuration      []Entry      `json:"configuration"`
+	Dependencies       []Dependency `json:"dependencies"`
+	DependencyVersion2 bool         `json:"dependency_version_2"`
+}
+
+// Entry configures how the catalog presents one variable.
+type Entry struct {
+	Key          string          `json:"key"`
+	Type         string          `json:"type"`
+	Required     *bool           `json:"required"`
+	DefaultValue json.RawMessage `json:"default_value"`
+	Hidden       bool            `json:"hidden"`
+	Options      []Option        `json:"options"`
+}
+
+// Option is a value offered in a drop-down.
+type Option struct {
+	DisplayName string `json:"displayname"`
+	Value       any    `json:"value"`
+}
+
+// Dependency is another deployable architecture that can be deployed with a flavor.
+type Dependency struct {
+	Name         string         `json:"name"`
+	ID           string         `json:"id"`
+	CatalogID    string         `json:"catalog_id"`
+	Version      string         `json:"version"`
+	Flavors      []string       `json:"flavors"`
+	Optional     bool           `json:"optional"`
+	InputMapping []InputMapping `json:"input_mapping"`
+}
+
+// InputMapping connects an input of the flavor with an input or output of a dependency.
+type InputMapping struct {
+	DependencyInput  string `json:"dependency_input"`
+	DependencyOutput string `json:"dependency_output"`
+	VersionInput     string `json:"version_input"`
+}
+
+// Load reads a catalog manifest.
+func Load(path string) (*Manifest, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(src, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Flavor returns the flavor with the name from any product.
+func (m *Manifest) Flavor(name string) (*Flavor, error) {
+	for _, product := range m.Products {
+		for i := range product.Flavors {
+			if product.Flavors[i].Name == name {
+				return &product.Flavors[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("flavor %q not found", name)
+}
+
+// catalogTypes are the entry types that fit each kind of Terraform type
+var catalogTypes = map[string][]string{
+	"string": {"string", "password", "multiline_secure_value"},
+	"number": {"number", "int", "float"},
+	"bool":   {"boolean"},
+	"list":   {"array"},
+	"object": {"object"},
+}
+
+// versionConstraint matches the dependency version constraints the catalog accepts, such as ^v1.2.3 or >=v2.0.0
+var versionConstraint = regexp.MustCompile(`^(\^|~|>=|<=|>|<|=)?v?\d+\.\d+\.\d+$`)
+
+// Check compares the flavor with the variables of its module and returns every problem found.
+func (f *Flavor) Check(m *tfconfig.Module) error {
+	var errs []error
+	report := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{f.Name}, args...)...))
+	}
+
+	listed := map[string]bool{}
+	for _, entry := range f.Configuration {
+		if listed[entry.Key] {
+			report("%s is configured more than once", entry.Key)
+		}
+		listed[entry.Key] = true
+		variable, ok := m.Variables[entry.Key]
+		if !ok {
+			report("%s is not a variable of %s", entry.Key, f.WorkingDirectory)
+			continue
+		}
+		for _, err := range entry.check(variable) {
+			report("%s: %w", entry.Key, err)
+		}
+	}
+	for _, name := range m.VariableOrder {
+		if !listed[name] {
+			report("variable %s is not in the configuration", name)
+		}
+	}
+
+	for _, dependency := range f.Dependencies {
+		for _, err := range dependency.check(listed) {
+			report("dependency %q: %w", dependency.Name, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e Entry) check(variable *tfconfig.Variable) []error {
+	var errs []error
+	if e.Type != "" && !slices.Contains(catalogTypes[kind(variable.Type)], e.Type) {
+		errs = append(errs, fmt.Errorf("type %q does not fit %s", e.Type, variable.Type.FriendlyName()))
+	}
+	if e.Required != nil && !*e.Required && variable.Required() {
+		errs = append(errs, errors.New("is not required in the catalog but has no default"))
+	}
+
+	def := variable.Default
+	if len(e.DefaultValue) > 0 {
+		value, err := decode(e.DefaultValue, variable)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("default_value: %w", err))
+		case def != cty.NilVal && !def.IsNull() && !value.Equals(def).True():
+			errs = append(errs, fmt.Errorf("default_value %s differs from the variable default %s", e.DefaultValue, render(def)))
+		default:
+			// the catalog can set a default for a variable that is required or defaults to null
+			def = value
+		}
+	}
+	if e.Hidden && def == cty.NilVal {
+		errs = append(errs, errors.New("is hidden but has no default, so it can never be set"))
+	}
+
+	if len(e.Options) > 0 && def != cty.NilVal && !def.IsNull() {
+		found := false
+		for _, option := range e.Options {
+			src, err := json.Marshal(option.Value)
+			if err != nil {
+				return append(errs, err)
+			}
+			value, err := decode(src, variable)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("option %s: %w", src, err))
+				continue
+			}
+			found = found || value.Equals(def).True()
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("default %s is not one of the options", render(def)))
+		}
+	}
+	return errs
+}
+
+func (d Dependency) check(listed map[string]bool) []error {
+	var errs []error
+	if d.Name == "" {
+		errs = append(errs, errors.New("has no name"))
+	}
+	if d.ID == "" && d.CatalogID == "" {
+		errs = append(errs, errors.New("has neither id nor catalog_id"))
+	}
+	if !versionConstraint.MatchString(d.Version) {
+		errs = append(errs, fmt.Errorf("version %q is not a version constraint", d.Version))
+	}
+	if len(d.Flavors) == 0 {
+		errs = append(errs, errors.New("lists no flavors"))
+	}
+	for _, mapping := range d.InputMapping {
+		if (mapping.DependencyInput == "") == (mapping.DependencyOutput == "") {
+			errs = append(errs, fmt.Errorf("input_mapping to %q needs exactly one of dependency_input and dependency_output", mapping.VersionInput))
+		}
+		if !listed[mapping.VersionInput] {
+			errs = append(errs, fmt.Errorf("input_mapping version_input %q is not in the configuration", mapping.VersionInput))
+		}
+	}
+	return errs
+}
+
+// kind groups a Terraform type by the catalog types that can present it
+func kind(t cty.Type) string {
+	switch {
+	case t == cty.String:
+		return "string"
+	case t == cty.Number:
+		return "number"
+	case t == cty.Bool:
+		return "bool"
+	case t.IsListType(), t.IsSetType(), t.IsTupleType():
+		return "list"
+	case t.IsMapType(), t.IsObjectType():
+		return "object"
+	default:
+		return ""
+	}
+}
+
+// decode converts a JSON value from the catalog to the variable type, applying optional attribute defaults
+func decode(src []byte, variable *tfconfig.Variable) (cty.Value, error) {
+	implied, err := ctyjson.ImpliedType(src)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	value, err := ctyjson.Unmarshal(src, implied)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if variable.Defaults != nil {
+		value = variable.Defaults.Apply(value)
+	}
+	return convert.Convert(value, variable.Type)
+}
+
+func render(value cty.Value) string {
+	src, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return value.GoString()
+	}
+	return string(src)
+}
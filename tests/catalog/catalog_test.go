@@ -0,0 +1,185 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+const testVariables = `
+variable "ibmcloud_api_key" {
+  type      = string
+  sensitive = true
+}
+variable "region" {
+  type    = string
+  default = "us-south"
+}
+variable "version" {
+  type    = string
+  default = null
+}
+variable "members" {
+  type    = number
+  default = 3
+}
+variable "tags" {
+  type    = list(string)
+  default = []
+}
+variable "scaling" {
+  type = object({
+    enabled = optional(bool, false)
+    percent = optional(number, 10)
+  })
+  default = {}
+}
+`
+
+const testManifest = `{
+  "products": [{
+    "name": "deploy-arch-test",
+    "flavors": [{
+      "name": "test",
+      "working_directory": "solutions/test",
+      "configuration": [
+        {"key": "ibmcloud_api_key", "type": "password"},
+        {"key": "region", "required": true, "options": [{"displayname": "Dallas", "value": "us-south"}, {"displayname": "Frankfurt", "value": "eu-de"}]},
+        {"key": "version", "default_value": "8.19"},
+        {"key": "members", "default_value": 3},
+        {"key": "tags", "type": "array", "hidden": true},
+        {"key": "scaling", "default_value": {"enabled": false}}
+      ],
+      "dependencies": [{
+        "name": "deploy-arch-kms",
+        "catalog_id": "7a4d68b4-cf8b-40cd-a3d1-f49aff526eb3",
+        "version": "^v5.0.0",
+        "flavors": ["fully-configurable"],
+        "input_mapping": [{"dependency_output": "kms_instance_crn", "version_input": "region"}]
+      }]
+    }]
+  }]
+}`
+
+func load(t *testing.T, manifest string) (*Flavor, *tfconfig.Module) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testVariables), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ibm_catalog.json"), []byte(manifest), 0o644))
+	m, err := tfconfig.LoadModule(dir)
+	require.NoError(t, err)
+	catalog, err := Load(filepath.Join(dir, "ibm_catalog.json"))
+	require.NoError(t, err)
+	flavor, err := catalog.Flavor("test")
+	require.NoError(t, err)
+	return flavor, m
+}
+
+func TestCheck(t *testing.T) {
+	flavor, m := load(t, testManifest)
+	assert.NoError(t, flavor.Check(m))
+
+	_, err := (&Manifest{}).Flavor("missing")
+	assert.Error(t, err)
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(f *Flavor)
+		want   string
+	}{
+		{
+			name:   "unknown key",
+			change: func(f *Flavor) { f.Configuration = append(f.Configuration, Entry{Key: "zone"}) },
+			want:   "test: zone is not a variable of solutions/test",
+		},
+		{
+			name:   "duplicate key",
+			change: func(f *Flavor) { f.Configuration = append(f.Configuration, Entry{Key: "region"}) },
+			want:   "test: region is configured more than once",
+		},
+		{
+			name:   "variable not listed",
+			change: func(f *Flavor) { f.Configuration = f.Configuration[1:] },
+			want:   "test: variable ibmcloud_api_key is not in the configuration",
+		},
+		{
+			name:   "type",
+			change: func(f *Flavor) { f.Configuration[3].Type = "array" },
+			want:   `test: members: type "array" does not fit number`,
+		},
+		{
+			name:   "required",
+			change: func(f *Flavor) { f.Configuration[0].Required = new(bool) },
+			want:   "test: ibmcloud_api_key: is not required in the catalog but has no default",
+		},
+		{
+			name:   "default",
+			change: func(f *Flavor) { f.Configuration[3].DefaultValue = []byte("5") },
+			want:   "test: members: default_value 5 differs from the variable default 3",
+		},
+		{
+			name:   "object default",
+			change: func(f *Flavor) { f.Configuration[5].DefaultValue = []byte(`{"percent": 20}`) },
+			want:   `test: scaling: default_value {"percent": 20} differs from the variable default {"enabled":false,"percent":10}`,
+		},
+		{
+			name:   "default of the wrong type",
+			change: func(f *Flavor) { f.Configuration[3].DefaultValue = []byte(`"three"`) },
+			want:   "test: members: default_value: a number is required",
+		},
+		{
+			name:   "hidden without default",
+			change: func(f *Flavor) { f.Configuration[0].Hidden = true },
+			want:   "test: ibmcloud_api_key: is hidden but has no default, so it can never be set",
+		},
+		{
+			name:   "default not an option",
+			change: func(f *Flavor) { f.Configuration[1].Options = f.Configuration[1].Options[1:] },
+			want:   `test: region: default "us-south" is not one of the options`,
+		},
+		{
+			name:   "dependency version",
+			change: func(f *Flavor) { f.Dependencies[0].Version = "latest" },
+			want:   `test: dependency "deploy-arch-kms": version "latest" is not a version constraint`,
+		},
+		{
+			name:   "dependency without catalog",
+			change: func(f *Flavor) { f.Dependencies[0].CatalogID = "" },
+			want:   `test: dependency "deploy-arch-kms": has neither id nor catalog_id`,
+		},
+		{
+			name:   "dependency without flavors",
+			change: func(f *Flavor) { f.Dependencies[0].Flavors = nil },
+			want:   `test: dependency "deploy-arch-kms": lists no flavors`,
+		},
+		{
+			name: "input mapping to both",
+			change: func(f *Flavor) {
+				f.Dependencies[0].InputMapping[0].DependencyInput = "region"
+			},
+			want: `test: dependency "deploy-arch-kms": input_mapping to "region" needs exactly one of dependency_input and dependency_output`,
+		},
+		{
+			name:   "input mapping to unknown input",
+			change: func(f *Flavor) { f.Dependencies[0].InputMapping[0].VersionInput = "kms_crn" },
+			want:   `test: dependency "deploy-arch-kms": input_mapping version_input "kms_crn" is not in the configuration`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			flavor, m := load(t, testManifest)
+			tc.change(flavor)
+			err := flavor.Check(m)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
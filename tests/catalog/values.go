@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// ValidationValuesTemplate is the file in each solution with the inputs used to validate it during catalog onboarding.
+const ValidationValuesTemplate = "catalogValidationValues.json.template"
+
+var placeholder = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
+
+// PermanentResourceKeys maps placeholders to their key in the common permanent resources, for placeholders
+// whose key is not simply the placeholder in lower case.
+var PermanentResourceKeys = map[string]string{
+	"HPCS_US_SOUTH_CRN": "hpcs_south_crn",
+}
+
+// Placeholders returns the placeholders of a template without the $, in order of first use.
+func Placeholders(template []byte) []string {
+	var names []string
+	for _, match := range placeholder.FindAllSubmatch(template, -1) {
+		if name := string(match[1]); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Values returns a value for every placeholder of the template, from the generated values or otherwise from the
+// permanent resources. It fails if a placeholder has no value.
+func Values(template []byte, permanentResources map[string]any, generated map[string]any) (map[string]any, error) {
+	values := map[string]any{}
+	var missing []string
+	for _, name := range Placeholders(template) {
+		if value, ok := generated[name]; ok {
+			values[name] = value
+			continue
+		}
+		key, ok := PermanentResourceKeys[name]
+		if !ok {
+			key = strings.ToLower(name)
+		}
+		if value, ok := permanentResources[key]; ok {
+			values[name] = value
+			continue
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no value for %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// Render replaces every placeholder with the JSON encoding of its value, as the pipeline does, and returns the
+// decoded inputs.
+func Render(template []byte, values map[string]any) (map[string]any, error) {
+	var errs []error
+	rendered := placeholder.ReplaceAllFunc(template, func(match []byte) []byte {
+		name := string(match[1:])
+		value, ok := values[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no value for %s", name))
+			return match
+		}
+		src, err := json.Marshal(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return match
+		}
+		return src
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var inputs map[string]any
+	if err := json.Unmarshal(rendered, &inputs); err != nil {
+		return nil, fmt.Errorf("rendered template is not a JSON object: %w", err)
+	}
+	return inputs, nil
+}
+
+// Inputs returns the inputs the catalog deploys the flavor with: the default_value of each configuration entry,
+// overridden by values.
+func (f *Flavor) Inputs(values map[string]any) (map[string]any, error) {
+	inputs := map[string]any{}
+	for _, entry := range f.Configuration {
+		if len(entry.DefaultValue) == 0 {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(entry.DefaultValue, &value); err != nil {
+			return nil, fmt.Errorf("%s: default_value: %w", entry.Key, err)
+		}
+		inputs[entry.Key] = value
+	}
+	for key, value := range values {
+		inputs[key] = value
+	}
+	return inputs, nil
+}
+
+// CheckInputs checks that every input is a variable of the module and converts to its type, that every required
+// variable has an input, and that the inputs pass the variable validations of the module.
+func CheckInputs(m *tfconfig.Module, inputs map[string]any) error {
+	var errs []error
+	values := map[string]cty.Value{}
+	for key, input := range inputs {
+		variable, ok := m.Variables[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s is not a variable", key))
+			continue
+		}
+		src, err := json.Marshal(input)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		value, err := decode(src, variable)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s does not fit %s: %w", key, src, variable.Type.FriendlyName(), err))
+			continue
+		}
+		values[key] = value
+	}
+	for _, name := range m.VariableOrder {
+		if _, ok := inputs[name]; !ok && m.Variables[name].Required() {
+			errs = append(errs, fmt.Errorf("required variable %s has no input", name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	all, err := m.ValuesFrom(values)
+	if err != nil {
+		return err
+	}
+	failures, err := m.ValidateValues(all)
+	if err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		errs = append(errs, errors.New(failure.String()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+const testTemplate = `{
+  "ibmcloud_api_key": $VALIDATION_APIKEY,
+  "region": "eu-de",
+  "tags": $TAGS,
+  "kms_crn": $HPCS_US_SOUTH_CRN,
+  "backup_kms_crn": $HPCS_US_SOUTH_CRN,
+  "members": $MEMBERS
+}`
+
+func TestPlaceholders(t *testing.T) {
+	assert.Equal(t, []string{"VALIDATION_APIKEY", "TAGS", "HPCS_US_SOUTH_CRN", "MEMBERS"}, Placeholders([]byte(testTemplate)))
+	assert.Empty(t, Placeholders([]byte(`{"region": "eu-de"}`)))
+}
+
+func TestValues(t *testing.T) {
+	permanentResources := map[string]any{"hpcs_south_crn": "crn:hpcs", "members": 3, "tags": []string{"permanent"}}
+	values, err := Values([]byte(testTemplate), permanentResources, map[string]any{"VALIDATION_APIKEY": "key", "TAGS": []string{"generated"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"VALIDATION_APIKEY": "key",
+		"TAGS":              []string{"generated"},
+		"HPCS_US_SOUTH_CRN": "crn:hpcs",
+		"MEMBERS":           3,
+	}, values)
+
+	_, err = Values([]byte(testTemplate), nil, map[string]any{"TAGS": nil})
+	assert.EqualError(t, err, "no value for VALIDATION_APIKEY, HPCS_US_SOUTH_CRN, MEMBERS")
+}
+
+func TestRender(t *testing.T) {
+	values := map[string]any{"VALIDATION_APIKEY": "key", "TAGS": []string{"a", "b"}, "HPCS_US_SOUTH_CRN": "crn:hpcs", "MEMBERS": 3}
+	inputs, err := Render([]byte(testTemplate), values)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"ibmcloud_api_key": "key",
+		"region":           "eu-de",
+		"tags":             []any{"a", "b"},
+		"kms_crn":          "crn:hpcs",
+		"backup_kms_crn":   "crn:hpcs",
+		"members":          float64(3),
+	}, inputs)
+
+	_, err = Render([]byte(testTemplate), map[string]any{"TAGS": nil})
+	assert.ErrorContains(t, err, "no value for VALIDATION_APIKEY")
+	_, err = Render([]byte(`{"region": $REGION,}`), map[string]any{"REGION": "eu-de"})
+	assert.ErrorContains(t, err, "rendered template is not a JSON object")
+}
+
+func TestInputs(t *testing.T) {
+	flavor := &Flavor{Configuration: []Entry{
+		{Key: "region", DefaultValue: []byte(`"us-south"`)},
+		{Key: "version", DefaultValue: []byte(`"8.19"`)},
+		{Key: "members"},
+	}}
+	inputs, err := flavor.Inputs(map[string]any{"region": "eu-de", "ibmcloud_api_key": "key"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"region": "eu-de", "version": "8.19", "ibmcloud_api_key": "key"}, inputs)
+}
+
+func TestCheckInputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testVariables+`
+variable "plan" {
+  type    = string
+  default = "standard"
+  validation {
+    condition     = contains(["standard", "premium"], var.plan)
+    error_message = "Unknown plan."
+  }
+}
+`), 0o644))
+	m, err := tfconfig.LoadModule(dir)
+	require.NoError(t, err)
+
+	assert.NoError(t, CheckInputs(m, map[string]any{"ibmcloud_api_key": "key", "members": float64(5), "tags": []any{"a"}, "scaling": map[string]any{"enabled": true}}))
+
+	err = CheckInputs(m, map[string]any{"members": "three", "zone": "1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `members: "three" does not fit number`)
+	assert.Contains(t, err.Error(), "zone is not a variable")
+	assert.Contains(t, err.Error(), "required variable ibmcloud_api_key has no input")
+
+	assert.EqualError(t, CheckInputs(m, map[string]any{"ibmcloud_api_key": "key", "plan": "free"}), "plan: Unknown plan.")
+}
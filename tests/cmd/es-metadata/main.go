@@ -0,0 +1,79 @@
+// Command es-metadata reads the Elasticsearch version of an instance like the es_metadata external data source of the
+// fully-configurable solution, which runs scripts/es_metadata.sh, but without jq and curl. It reads the query of the
+// data source from stdin and prints the result to stdout:
+//
+//	{"url": "https://host:port", "username": "admin", "password": "...", "ca_cert_b64": "..."}
+//	{"version_number": "8.19.3"}
+//
+// The version_number is null when the instance reports no version, as the script prints it. On failure it prints
+// {"error": "..."} to stderr and exits with 1, which Terraform reports as the error. The tests run the script and
+// this program against the same fake cluster, so that they do not drift apart.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+)
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	err := run(ctx, os.Stdin, os.Stdout)
+	cancel()
+	if err != nil {
+		_ = json.NewEncoder(os.Stderr).Encode(map[string]string{"error": err.Error()})
+		os.Exit(1)
+	}
+}
+
+// query is the query of the data source; the external protocol only passes strings
+type query struct {
+	URL       string `json:"url"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	CACertB64 string `json:"ca_cert_b64"`
+}
+
+func run(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	var q query
+	decoder := json.NewDecoder(stdin)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&q); err != nil {
+		return fmt.Errorf("reading the query: %w", err)
+	}
+	var missing []string
+	for name, value := range map[string]string{"url": q.URL, "username": q.Username, "password": q.Password, "ca_cert_b64": q.CACertB64} {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("the query has no %v", missing)
+	}
+
+	c, err := credentials.FromURL("url", q.URL, q.CACertB64)
+	if err != nil {
+		return err
+	}
+	c.Username, c.Password = q.Username, q.Password
+	client, err := elasticsearch.New(c)
+	if err != nil {
+		return err
+	}
+	info, err := client.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Version.Number == "" {
+		return json.NewEncoder(stdout).Encode(map[string]any{"version_number": nil})
+	}
+	return json.NewEncoder(stdout).Encode(map[string]string{"version_number": info.Version.Number})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+// serve starts a fake cluster and returns the query for it
+func serve(t *testing.T, version string) (query, *elasticsearchtest.Server) {
+	t.Helper()
+	server := elasticsearchtest.NewServer(elasticsearchtest.Options{Version: version, Users: map[string]string{"admin": "secret"}}) // pragma: allowlist secret
+	t.Cleanup(server.Close)
+	return query{
+		URL:       server.URL,
+		Username:  "admin",
+		Password:  "secret", // pragma: allowlist secret
+		CACertB64: server.CertificateBase64(),
+	}, server
+}
+
+// serveWithoutVersion starts a fake cluster whose root endpoint has no version and returns the query for it
+func serveWithoutVersion(t *testing.T) query {
+	t.Helper()
+	server := elasticsearchtest.NewServer(elasticsearchtest.Options{
+		Users: map[string]string{"admin": "secret"}, // pragma: allowlist secret
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"version": {}}`))
+		}),
+	})
+	t.Cleanup(server.Close)
+	return query{URL: server.URL, Username: "admin", Password: "secret", CACertB64: server.CertificateBase64()} // pragma: allowlist secret
+}
+
+func runQuery(t *testing.T, q any) (string, error) {
+	t.Helper()
+	src, err := json.Marshal(q)
+	require.NoError(t, err)
+	var stdout bytes.Buffer
+	err = run(context.Background(), bytes.NewReader(src), &stdout)
+	return stdout.String(), err
+}
+
+func TestRun(t *testing.T) {
+	q, _ := serve(t, "8.19.3")
+	stdout, err := runQuery(t, q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version_number": "8.19.3"}`, stdout)
+}
+
+func TestRunFails(t *testing.T) {
+	q, _ := serve(t, "8.19.3")
+	unavailable, server := serve(t, "8.19.3")
+	server.Inject(elasticsearchtest.Fault{Path: "/", Status: http.StatusServiceUnavailable})
+
+	wrongPassword := q
+	wrongPassword.Password = "wrong" // pragma: allowlist secret
+	untrusted := q
+	untrusted.CACertB64 = base64.StdEncoding.EncodeToString([]byte("not a certificate"))
+
+	tests := []struct {
+		name    string
+		query   any
+		wantErr string
+	}{
+		{name: "wrong password", query: wrongPassword, wantErr: "GET /: 401 Unauthorized: {\"error\":{\"reason\":\"unable to authenticate user [admin] for REST request [/]\""},
+		{name: "unavailable", query: unavailable, wantErr: "GET /: 503 Service Unavailable"},
+		{name: "not a certificate", query: untrusted, wantErr: "has no PEM certificate"},
+		{name: "missing fields", query: map[string]string{"url": q.URL}, wantErr: "the query has no [ca_cert_b64 password username]"},
+		{name: "unknown field", query: map[string]string{"uri": q.URL}, wantErr: `reading the query: json: unknown field "uri"`},
+		{name: "not https", query: query{URL: strings.Replace(q.URL, "https", "http", 1), Username: "a", Password: "b", CACertB64: "c"}, wantErr: "url: the URL is not https"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, err := runQuery(t, tc.query)
+			assert.ErrorContains(t, err, tc.wantErr)
+			assert.Empty(t, stdout, "nothing is printed on failure")
+		})
+	}
+}
+
+// script is the program of the es_metadata data source of the fully-configurable solution
+var script = filepath.Join("..", "..", "..", "solutions", "fully-configurable", "scripts", "es_metadata.sh")
+
+// runScript runs the script like the es_metadata data source does and returns its stdout
+func runScript(t *testing.T, q query) (string, error) {
+	t.Helper()
+	for _, binary := range []string{"bash", "curl", "jq", "base64"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			t.Skipf("the script needs %s: %v", binary, err)
+		}
+	}
+	src, err := json.Marshal(q)
+	require.NoError(t, err)
+	cmd := exec.Command("bash", script)
+	cmd.Stdin = bytes.NewReader(src)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// TestScriptParity runs the script and the program against the same clusters, as they must agree
+func TestScriptParity(t *testing.T) {
+	q, _ := serve(t, "8.19.3")
+	wrongPassword := q
+	wrongPassword.Password = "wrong" // pragma: allowlist secret
+
+	tests := []struct {
+		name    string
+		query   query
+		want    string
+		wantErr bool
+	}{
+		{name: "version", query: q, want: `{"version_number": "8.19.3"}`},
+		{name: "no version", query: serveWithoutVersion(t), want: `{"version_number": null}`},
+		{name: "wrong password", query: wrongPassword, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scriptOut, scriptErr := runScript(t, tc.query)
+			programOut, programErr := runQuery(t, tc.query)
+			if tc.wantErr {
+				assert.Error(t, scriptErr, "script")
+				assert.Error(t, programErr, "program")
+				return
+			}
+			require.NoError(t, scriptErr, "script")
+			require.NoError(t, programErr, "program")
+			assert.JSONEq(t, tc.want, scriptOut, "script")
+			assert.JSONEq(t, tc.want, programOut, "program")
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Command parity reports the root module inputs that the deployable architectures do not wire through, defaults
+// that differ from the root module, and descriptions that differ between the deployable architectures.
+//
+//	go run ./cmd/parity -repo .. fully-configurable fully-configurable-gen2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/parity"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+func main() {
+	repo := flag.String("repo", "..", "path to the repository root")
+	flag.Parse()
+	names := flag.Args()
+	if len(names) == 0 {
+		names = []string{"fully-configurable", "fully-configurable-gen2"}
+	}
+
+	findings, err := run(*repo, names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, finding := range findings {
+		fmt.Println(finding)
+	}
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run(repo string, names []string) ([]parity.Finding, error) {
+	root, err := tfconfig.LoadModule(repo)
+	if err != nil {
+		return nil, err
+	}
+	solutions, err := parity.Load(repo, names...)
+	if err != nil {
+		return nil, err
+	}
+	return parity.Check(root, solutions)
+}
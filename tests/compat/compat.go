@@ -0,0 +1,159 @@
+// Package compat encodes the Elasticsearch version, plan and region rules documented on the
+// `elasticsearch_version` and `plan` inputs of the root module.
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+// Plans accepted by the `plan` input.
+const (
+	PlanEnterprise     = "enterprise"
+	PlanPlatinum       = "platinum"
+	PlanEnterpriseGen2 = "enterprise-gen2"
+)
+
+// Plans lists every plan accepted by the `plan` input.
+var Plans = []string{PlanEnterprise, PlanPlatinum, PlanEnterpriseGen2}
+
+// Gen2Regions lists the regions where gen2 instances are available.
+var Gen2Regions = []string{"eu-de", "eu-fr2"}
+
+// versions from 9.1 onwards must use the Enterprise Platinum plan
+var platinumOnlyFrom = versions.MustParse("9.1")
+
+// Query is a version, plan and region combination to check.
+type Query struct {
+	Version versions.ElasticsearchVersion
+	Plan    string
+	Region  string
+}
+
+// Rule is a single documented restriction.
+type Rule struct {
+	Name string
+	// Check returns an error describing the violation, or nil if the combination satisfies the rule
+	Check func(q Query) error
+}
+
+// IsGen2 reports whether the plan provisions a gen2 instance, mirroring `local.is_gen2` in main.tf.
+func IsGen2(plan string) bool {
+	return strings.HasSuffix(plan, "-gen2")
+}
+
+// Rules are evaluated in order by Check.
+var Rules = []Rule{
+	{
+		Name: "supported plan",
+		Check: func(q Query) error {
+			if !slices.Contains(Plans, q.Plan) {
+				return fmt.Errorf("plan %q is not one of %s", q.Plan, strings.Join(Plans, ", "))
+			}
+			return nil
+		},
+	},
+	{
+		Name: "9.1 or later requires platinum",
+		Check: func(q Query) error {
+			if IsGen2(q.Plan) || q.Version.LessThan(platinumOnlyFrom) {
+				return nil
+			}
+			if q.Plan != PlanPlatinum {
+				return fmt.Errorf("version %s requires the %s plan, got %q", q.Version, PlanPlatinum, q.Plan)
+			}
+			return nil
+		},
+	},
+	{
+		Name: "gen2 region",
+		Check: func(q Query) error {
+			if IsGen2(q.Plan) && !slices.Contains(Gen2Regions, q.Region) {
+				return fmt.Errorf("plan %s is only available in %s, got region %q", q.Plan, strings.Join(Gen2Regions, ", "), q.Region)
+			}
+			return nil
+		},
+	},
+}
+
+// Check returns an error listing every rule the combination violates, or nil if it is allowed.
+func Check(version string, plan string, region string) error {
+	parsed, err := versions.Parse(version)
+	if err != nil {
+		return err
+	}
+	q := Query{Version: parsed, Plan: plan, Region: region}
+
+	var errs []error
+	for _, rule := range Rules {
+		if err := rule.Check(q); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rule.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Allowed reports whether the version is allowed on the plan in the region.
+func Allowed(version string, plan string, region string) bool {
+	return Check(version, plan, region) == nil
+}
+
+// Filter returns the offered versions that are allowed on the plan in the region, in their original order.
+// Gen2 has its own version list, so offered must come from the catalog lookup for the same plan.
+func Filter(offered []string, plan string, region string) ([]string, error) {
+	if _, err := versions.ParseAll(offered); err != nil {
+		return nil, err
+	}
+	var allowed []string
+	for _, version := range offered {
+		if Allowed(version, plan, region) {
+			allowed = append(allowed, version)
+		}
+	}
+	return allowed, nil
+}
+
+// CheckPinnedDefault returns an error if a pinned default version is no longer offered, or if the catalog offers
+// a newer version that is allowed on the plan in the region, which the default should move to. Newer versions that
+// the module does not allow, or that are listed in skipped, are not reported.
+func CheckPinnedDefault(pinned string, offered []string, skipped []string, plan string, region string) error {
+	pinnedVersion, err := versions.Parse(pinned)
+	if err != nil {
+		return err
+	}
+	list, err := versions.ParseAll(offered)
+	if err != nil {
+		return err
+	}
+	skippedList, err := versions.ParseAll(skipped)
+	if err != nil {
+		return err
+	}
+
+	isOffered := false
+	var allowed []versions.ElasticsearchVersion
+	for _, version := range list {
+		if version.Compare(pinnedVersion) == 0 {
+			isOffered = true
+		}
+		isSkipped := slices.ContainsFunc(skippedList, func(s versions.ElasticsearchVersion) bool { return s.Compare(version) == 0 })
+		if !isSkipped && Allowed(version.String(), plan, region) {
+			allowed = append(allowed, version)
+		}
+	}
+	latest, err := versions.Latest(allowed)
+	if err != nil {
+		return fmt.Errorf("no version offered by the catalog (offered: %s) is allowed on plan %s in %s", strings.Join(offered, ", "), plan, region)
+	}
+	if !isOffered {
+		return fmt.Errorf("pinned default version %s is no longer offered by the catalog (offered: %s), update the default to %s", pinned, strings.Join(offered, ", "), latest)
+	}
+	if pinnedVersion.LessThan(latest) {
+		return fmt.Errorf("pinned default version %s is older than the latest allowed version %s, update the default to %s", pinned, latest, latest)
+	}
+	return nil
+}
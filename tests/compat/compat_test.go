@@ -0,0 +1,114 @@
+package compat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Each case pins a rule from the `elasticsearch_version` and `plan` descriptions in variables.tf
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		plan    string
+		region  string
+		allowed bool
+	}{
+		// supported plans
+		{name: "enterprise", version: "8.15", plan: "enterprise", region: "us-south", allowed: true},
+		{name: "platinum", version: "8.15", plan: "platinum", region: "us-south", allowed: true},
+		{name: "enterprise-gen2", version: "8.19", plan: "enterprise-gen2", region: "eu-de", allowed: true},
+		{name: "standard plan is not supported", version: "8.7", plan: "standard", region: "us-south", allowed: false},
+		{name: "empty plan is not supported", version: "8.15", plan: "", region: "us-south", allowed: false},
+
+		// 9.1 requires Enterprise Platinum
+		{name: "8.19 on enterprise", version: "8.19", plan: "enterprise", region: "us-south", allowed: true},
+		{name: "8.19.11 on enterprise", version: "8.19.11", plan: "enterprise", region: "us-south", allowed: true},
+		{name: "9.1 on enterprise", version: "9.1", plan: "enterprise", region: "us-south", allowed: false},
+		{name: "9.1 on platinum", version: "9.1", plan: "platinum", region: "eu-de", allowed: true},
+		{name: "9.2 on enterprise", version: "9.2", plan: "enterprise", region: "us-south", allowed: false},
+		{name: "9.1 on enterprise-gen2 is governed by the gen2 version list", version: "9.1", plan: "enterprise-gen2", region: "eu-de", allowed: true},
+
+		// gen2 regions
+		{name: "gen2 in eu-fr2", version: "8.19", plan: "enterprise-gen2", region: "eu-fr2", allowed: true},
+		{name: "gen2 in us-south", version: "8.19", plan: "enterprise-gen2", region: "us-south", allowed: false},
+		{name: "classic in any region", version: "8.19", plan: "enterprise", region: "jp-tok", allowed: true},
+
+		// invalid versions
+		{name: "garbage version", version: "latest", plan: "platinum", region: "us-south", allowed: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Check(tc.version, tc.plan, tc.region)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, tc.allowed, Allowed(tc.version, tc.plan, tc.region))
+		})
+	}
+}
+
+func TestCheckReportsEveryViolation(t *testing.T) {
+	err := Check("9.1", "standard", "us-south")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "supported plan")
+	assert.Contains(t, err.Error(), "9.1 or later")
+}
+
+func TestFilter(t *testing.T) {
+	offered := []string{"8.12", "9.1", "8.19"}
+
+	enterprise, err := Filter(offered, "enterprise", "us-south")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"8.12", "8.19"}, enterprise)
+
+	platinum, err := Filter(offered, "platinum", "us-south")
+	require.NoError(t, err)
+	assert.Equal(t, offered, platinum)
+
+	gen2, err := Filter(offered, "enterprise-gen2", "us-south")
+	require.NoError(t, err)
+	assert.Empty(t, gen2)
+
+	_, err = Filter([]string{"8.12", "nine"}, "platinum", "us-south")
+	assert.Error(t, err)
+}
+
+func TestIsGen2(t *testing.T) {
+	assert.True(t, IsGen2("enterprise-gen2"))
+	assert.False(t, IsGen2("enterprise"))
+	assert.False(t, IsGen2("gen2-enterprise"))
+}
+
+func TestCheckPinnedDefault(t *testing.T) {
+	assert.NoError(t, CheckPinnedDefault("8.0", []string{"8.0"}, nil, "enterprise-gen2", "eu-de"))
+	assert.NoError(t, CheckPinnedDefault("8.19", []string{"8.15", "8.19.0"}, nil, "enterprise", "us-south"))
+	assert.NoError(t, CheckPinnedDefault("8.0", []string{"8.0", "8.19.11"}, []string{"8.19.11"}, "enterprise-gen2", "eu-de"), "8.19.11 is skipped")
+	assert.NoError(t, CheckPinnedDefault("8.19", []string{"8.19", "9.1"}, nil, "enterprise", "us-south"), "9.1 is not allowed on enterprise")
+
+	err := CheckPinnedDefault("8.0", []string{"8.15", "8.19"}, nil, "enterprise-gen2", "eu-de")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no longer offered")
+	assert.Contains(t, err.Error(), "update the default to 8.19")
+
+	err = CheckPinnedDefault("8.0", []string{"8.0", "8.19.11"}, nil, "enterprise-gen2", "eu-de")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "older than the latest allowed version 8.19.11")
+
+	err = CheckPinnedDefault("8.0", []string{"8.0", "8.19.11", "8.19.12"}, []string{"8.19.11"}, "enterprise-gen2", "eu-de")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "older than the latest allowed version 8.19.12")
+
+	err = CheckPinnedDefault("8.0", []string{"8.19.11"}, []string{"8.19.11"}, "enterprise-gen2", "eu-de")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no version offered by the catalog (offered: 8.19.11) is allowed on plan enterprise-gen2 in eu-de")
+
+	assert.Error(t, CheckPinnedDefault("8.0", nil, nil, "enterprise-gen2", "eu-de"))
+	assert.Error(t, CheckPinnedDefault("latest", []string{"8.0"}, nil, "enterprise-gen2", "eu-de"))
+	assert.Error(t, CheckPinnedDefault("8.0", []string{"8.0"}, []string{"next"}, "enterprise-gen2", "eu-de"))
+}
@@ -0,0 +1,49 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Backoff retries requests with a delay that doubles after every attempt.
+type Backoff struct {
+	// Attempts is the number of attempts, including the first one
+	Attempts int
+	// Delay is the delay before the second attempt
+	Delay time.Duration
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not Retryable or the attempts are used up. It returns
+// the number of attempts and the last error.
+func (b Backoff) Retry(ctx context.Context, fn func() error) (int, error) {
+	delay := b.Delay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= b.Attempts || !Retryable(err) {
+			return attempt, err
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Retryable reports whether a request may succeed when it is sent again: it failed to reach the cluster, or the
+// cluster answered 408, 429 or 5xx. A canceled or expired context is not retryable.
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	}
+	var transport *url.Error
+	return errors.As(err, &transport)
+}
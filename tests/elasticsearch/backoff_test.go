@@ -0,0 +1,75 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &url.Error{Op: "Get", URL: "https://host:1/", Err: errors.New("connection refused")}, want: true},
+		{err: errors.New("decoding the response"), want: false},
+		{err: &Error{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{err: &Error{StatusCode: http.StatusTooManyRequests}, want: true},
+		{err: &Error{StatusCode: http.StatusRequestTimeout}, want: true},
+		{err: &Error{StatusCode: http.StatusBadRequest}, want: false},
+		{err: &Error{StatusCode: http.StatusUnauthorized}, want: false},
+		{err: context.DeadlineExceeded, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.want, Retryable(tc.err))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	backoff := Backoff{Attempts: 4, Delay: time.Millisecond}
+	unavailable := &Error{StatusCode: http.StatusServiceUnavailable}
+
+	var calls []time.Time
+	attempts, err := backoff.Retry(context.Background(), func() error {
+		calls = append(calls, time.Now())
+		return unavailable
+	})
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, unavailable, err)
+	assert.Len(t, calls, 4)
+	for i := 1; i < len(calls); i++ {
+		assert.GreaterOrEqual(t, calls[i].Sub(calls[i-1]), time.Duration(1<<(i-1))*time.Millisecond, "the delay doubles")
+	}
+
+	calls = nil
+	attempts, err = backoff.Retry(context.Background(), func() error {
+		calls = append(calls, time.Now())
+		if len(calls) < 3 {
+			return unavailable
+		}
+		return nil
+	})
+	assert.Equal(t, 3, attempts)
+	assert.NoError(t, err)
+
+	attempts, err = backoff.Retry(context.Background(), func() error {
+		return &Error{StatusCode: http.StatusBadRequest}
+	})
+	assert.Equal(t, 1, attempts, "permanent errors are not retried")
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts, err = Backoff{Attempts: 4, Delay: time.Hour}.Retry(ctx, func() error { return unavailable })
+	assert.Equal(t, 1, attempts)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, unavailable)
+}
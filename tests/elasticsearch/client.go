@@ -0,0 +1,158 @@
+// Package elasticsearch is a small client for the Elasticsearch REST API of a deployed instance. It only covers
+// what the tests and tools in this repository need, and verifies TLS with the CA certificate of the instance.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+)
+
+// Client sends requests to one instance as one user.
+type Client struct {
+	url      string
+	username string
+	password string
+	http     *http.Client
+}
+
+// New returns a client for the instance and user of the credentials. Classic instances are verified with their CA
+// certificate, gen2 instances with the system pool. Requests are only limited by their context, since some of them,
+// such as installing a trained model, take minutes.
+func New(c credentials.Credentials) (*Client, error) {
+	pool, err := c.CertPool()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &Client{
+		url:      c.URL(),
+		username: c.Username,
+		password: c.Password,
+		http:     &http.Client{Transport: transport},
+	}, nil
+}
+
+// URL returns the URL of the instance.
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Error is a response with a status code outside 2xx.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Body is the response body, the Elasticsearch error document in most cases
+	Body string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), strings.TrimSpace(e.Body))
+}
+
+// NDJSON is a request body of one JSON document per line, as the _bulk API expects.
+type NDJSON []any
+
+// Do sends a request and decodes the JSON response into out, unless out is nil. The body in is encoded as JSON, or
+// line by line if it is NDJSON; a nil body sends no body. A status code outside 2xx is returned as an *Error.
+func (c *Client) Do(ctx context.Context, method string, path string, in any, out any) error {
+	var body io.Reader
+	contentType := "application/json"
+	switch in := in.(type) {
+	case nil:
+	case NDJSON:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, line := range in {
+			if err := encoder.Encode(line); err != nil {
+				return fmt.Errorf("%s %s: encoding the request: %w", method, path, err)
+			}
+		}
+		body = &buf
+		contentType = "application/x-ndjson"
+	default:
+		src, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("%s %s: encoding the request: %w", method, path, err)
+		}
+		body = bytes.NewReader(src)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+"/"+strings.TrimPrefix(path, "/"), body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	src, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: reading the response: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Error{Method: method, Path: path, StatusCode: resp.StatusCode, Body: string(src)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(src, out); err != nil {
+		return fmt.Errorf("%s %s: decoding the response: %w", method, path, err)
+	}
+	return nil
+}
+
+// Info is the response of the root endpoint.
+type Info struct {
+	ClusterName string `json:"cluster_name"`
+	Version     struct {
+		Number string `json:"number"`
+	} `json:"version"`
+}
+
+// Info returns the cluster name and version.
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	var info Info
+	err := c.Do(ctx, http.MethodGet, "/", nil, &info)
+	return info, err
+}
+
+// Health is the response of _cluster/health.
+type Health struct {
+	ClusterName   string `json:"cluster_name"`
+	Status        string `json:"status"`
+	TimedOut      bool   `json:"timed_out"`
+	NumberOfNodes int    `json:"number_of_nodes"`
+}
+
+// Health waits up to timeout for the cluster to reach status, "green" or "yellow", and returns its health. The
+// health is returned with TimedOut set if the status is not reached in time.
+func (c *Client) Health(ctx context.Context, status string, timeout time.Duration) (Health, error) {
+	var health Health
+	path := fmt.Sprintf("/_cluster/health?wait_for_status=%s&timeout=%ds", status, int(timeout.Seconds()))
+	err := c.Do(ctx, http.MethodGet, path, nil, &health)
+	// the cluster answers 408 with the health document when the status is not reached in time
+	var e *Error
+	if errors.As(err, &e) && e.StatusCode == http.StatusRequestTimeout && json.Unmarshal([]byte(e.Body), &health) == nil {
+		return health, nil
+	}
+	return health, err
+}
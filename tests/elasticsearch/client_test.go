@@ -0,0 +1,126 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+)
+
+// serve starts a TLS server and returns credentials for it with its self-signed certificate
+func serve(t *testing.T, handler http.HandlerFunc) credentials.Credentials {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return credentials.Credentials{
+		Name:              "admin",
+		Username:          "admin",
+		Password:          "secret", // pragma: allowlist secret
+		Hostname:          host,
+		Port:              portNumber,
+		CertificateBase64: base64.StdEncoding.EncodeToString(certificate),
+	}
+}
+
+func TestDo(t *testing.T) {
+	var gotBody, gotContentType string
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" { // pragma: allowlist secret
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		src, _ := io.ReadAll(r.Body)
+		gotBody, gotContentType = string(src), r.Header.Get("Content-Type")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`{"cluster_name": "test", "version": {"number": "8.19.3"}}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "index_not_found_exception"}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	})
+	client, err := New(c)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	info, err := client.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "8.19.3", info.Version.Number)
+	assert.Empty(t, gotContentType, "no body, no content type")
+
+	require.NoError(t, client.Do(ctx, http.MethodPost, "/index/_search", map[string]any{"size": 0}, nil))
+	assert.Equal(t, `{"size":0}`, gotBody)
+	assert.Equal(t, "application/json", gotContentType)
+
+	require.NoError(t, client.Do(ctx, http.MethodPost, "/_bulk", NDJSON{map[string]any{"index": map[string]any{}}, map[string]any{"a": 1}}, nil))
+	assert.Equal(t, "{\"index\":{}}\n{\"a\":1}\n", gotBody)
+	assert.Equal(t, "application/x-ndjson", gotContentType)
+
+	err = client.Do(ctx, http.MethodDelete, "/missing", nil, nil)
+	var e *Error
+	require.True(t, errors.As(err, &e), err)
+	assert.Equal(t, http.StatusNotFound, e.StatusCode)
+	assert.Equal(t, "DELETE /missing: 404 Not Found: {\"error\": \"index_not_found_exception\"}", e.Error())
+
+	c.Password = "wrong" // pragma: allowlist secret
+	wrong, err := New(c)
+	require.NoError(t, err)
+	_, err = wrong.Info(ctx)
+	require.True(t, errors.As(err, &e), err)
+	assert.Equal(t, http.StatusUnauthorized, e.StatusCode)
+}
+
+// Without the certificate of the instance, as on gen2, the system pool must not trust it
+func TestVerifiesCertificate(t *testing.T) {
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.CertificateBase64 = ""
+	client, err := New(c)
+	require.NoError(t, err)
+	_, err = client.Info(context.Background())
+	assert.ErrorContains(t, err, "certificate")
+}
+
+func TestHealth(t *testing.T) {
+	var gotQuery string
+	c := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		if r.URL.Query().Get("wait_for_status") == "green" {
+			w.WriteHeader(http.StatusRequestTimeout)
+			_, _ = w.Write([]byte(`{"status": "yellow", "timed_out": true, "number_of_nodes": 3}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "yellow", "timed_out": false, "number_of_nodes": 3}`))
+	})
+	client, err := New(c)
+	require.NoError(t, err)
+
+	health, err := client.Health(context.Background(), "yellow", 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "wait_for_status=yellow&timeout=30s", gotQuery)
+	assert.False(t, health.TimedOut)
+
+	health, err = client.Health(context.Background(), "green", time.Second)
+	require.NoError(t, err, "a timeout is reported in the health")
+	assert.True(t, health.TimedOut)
+	assert.Equal(t, "yellow", health.Status)
+}
@@ -0,0 +1,267 @@
+package elasticsearchtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// index is an index and its documents by ID
+type index struct {
+	mappings  map[string]any
+	documents map[string]map[string]any
+}
+
+// Documents returns the number of documents in the index, and whether it exists.
+func (s *Server) Documents(name string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.indices[name]
+	if !ok {
+		return 0, false
+	}
+	return len(i.documents), true
+}
+
+// validIndexName reports why the name cannot be an index, or "" when it can
+func validIndexName(name string) string {
+	switch {
+	case strings.HasPrefix(name, "_") || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "+"):
+		return "must not start with '_', '-', or '+'"
+	case strings.ToLower(name) != name:
+		return "must be lowercase"
+	case strings.ContainsAny(name, `\/*?"<>| ,#:`):
+		return `must not contain the following characters [ , ", *, \, <, |, ,, >, /, ?, :, #]`
+	}
+	return ""
+}
+
+func (s *Server) createIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("index")
+	if reason := validIndexName(name); reason != "" {
+		writeError(w, http.StatusBadRequest, "invalid_index_name_exception", fmt.Sprintf("Invalid index name [%s], %s", name, reason))
+		return
+	}
+	var body struct {
+		Mappings map[string]any `json:"mappings"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indices[name]; ok {
+		writeError(w, http.StatusBadRequest, "resource_already_exists_exception", fmt.Sprintf("index [%s] already exists", name))
+		return
+	}
+	s.indices[name] = &index{mappings: body.Mappings, documents: map[string]map[string]any{}}
+	writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true, "shards_acknowledged": true, "index": name})
+}
+
+func (s *Server) deleteIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("index")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indices[name]; !ok {
+		writeError(w, http.StatusNotFound, "index_not_found_exception", fmt.Sprintf("no such index [%s]", name))
+		return
+	}
+	delete(s.indices, name)
+	writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+}
+
+// bulk runs index, create and delete actions. The index of an action defaults to the index of the path, and a
+// missing index is created as the cluster does. Documents are searchable immediately, whatever the refresh.
+func (s *Server) bulk(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, http.StatusNotAcceptable, "media_type_header_exception", "Content-Type header ["+r.Header.Get("Content-Type")+"] is not supported")
+		return
+	}
+	var lines [][]byte
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(strings.TrimSpace(string(line))) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []any
+	failed := false
+	for len(lines) > 0 {
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(lines[0], &action); err != nil || len(action) != 1 {
+			writeError(w, http.StatusBadRequest, "illegal_argument_exception", fmt.Sprintf("Malformed action/metadata line [%s]", lines[0]))
+			return
+		}
+		lines = lines[1:]
+		for op, meta := range action {
+			if meta.Index == "" {
+				meta.Index = r.PathValue("index")
+			}
+			var source map[string]any
+			if op != "delete" {
+				if len(lines) == 0 || json.Unmarshal(lines[0], &source) != nil {
+					writeError(w, http.StatusBadRequest, "illegal_argument_exception", "The bulk request must be terminated by a newline [\\n]")
+					return
+				}
+				lines = lines[1:]
+			}
+			status, result := s.apply(op, meta.Index, &meta.ID, source)
+			item := map[string]any{"_index": meta.Index, "_id": meta.ID, "status": status}
+			if status >= 300 {
+				failed = true
+				item["error"] = map[string]any{"type": result, "reason": fmt.Sprintf("[%s]: %s", meta.ID, result)}
+			} else {
+				item["result"] = result
+			}
+			items = append(items, map[string]any{op: item})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"took": 1, "errors": failed, "items": items})
+}
+
+// apply runs a bulk action, generating the ID of indexed documents that have none, and returns the status and the
+// result or error type of the item
+func (s *Server) apply(op string, indexName string, id *string, source map[string]any) (int, string) {
+	if reason := validIndexName(indexName); reason != "" {
+		return http.StatusBadRequest, "invalid_index_name_exception"
+	}
+	i, ok := s.indices[indexName]
+	if op == "delete" {
+		if !ok || i.documents[*id] == nil {
+			return http.StatusNotFound, "not_found"
+		}
+		delete(i.documents, *id)
+		return http.StatusOK, "deleted"
+	}
+	if op != "index" && op != "create" {
+		return http.StatusBadRequest, "illegal_argument_exception"
+	}
+	if !ok {
+		i = &index{documents: map[string]map[string]any{}}
+		s.indices[indexName] = i
+	}
+	if *id == "" {
+		s.documentID++
+		*id = strconv.Itoa(s.documentID)
+	}
+	if _, exists := i.documents[*id]; exists {
+		if op == "create" {
+			return http.StatusConflict, "version_conflict_engine_exception"
+		}
+		i.documents[*id] = source
+		return http.StatusOK, "updated"
+	}
+	i.documents[*id] = source
+	return http.StatusCreated, "created"
+}
+
+// search supports the match_all query, the match query on a text field, which matches the documents that share a
+// lowercase word with the query, and the sparse_vector and text_expansion queries on a field that an inference
+// processor expanded, which match the documents that share a token with the expanded query. Hits are sorted by ID.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Size  *int                      `json:"size"`
+		Query map[string]map[string]any `json:"query"`
+	}{}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	size := 10
+	if body.Size != nil {
+		size = *body.Size
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var match func(map[string]any) bool
+	switch {
+	case body.Query == nil || body.Query["match_all"] != nil:
+		match = func(map[string]any) bool { return true }
+	case body.Query["match"] != nil && len(body.Query["match"]) == 1:
+		for field, query := range body.Query["match"] {
+			if q, ok := query.(map[string]any); ok {
+				query = q["query"]
+			}
+			words := tokens(fmt.Sprint(query))
+			match = func(source map[string]any) bool {
+				value, ok := source[field].(string)
+				if !ok {
+					return false
+				}
+				for word := range tokens(value) {
+					if words[word] {
+						return true
+					}
+				}
+				return false
+			}
+		}
+	case body.Query["sparse_vector"] != nil || body.Query["text_expansion"] != nil:
+		field, id, text, err := sparseQuery(body.Query)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "parsing_exception", err.Error())
+			return
+		}
+		if err := s.infer(id); err != nil {
+			writeError(w, http.StatusConflict, "status_exception", err.Error())
+			return
+		}
+		match = sparseMatch(field, text)
+	default:
+		writeError(w, http.StatusBadRequest, "parsing_exception", "elasticsearchtest supports the match_all, match, sparse_vector and text_expansion queries only")
+		return
+	}
+
+	name := r.PathValue("index")
+	i, ok := s.indices[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "index_not_found_exception", fmt.Sprintf("no such index [%s]", name))
+		return
+	}
+	ids := make([]string, 0, len(i.documents))
+	for id := range i.documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	hits := []any{}
+	total := 0
+	for _, id := range ids {
+		if !match(i.documents[id]) {
+			continue
+		}
+		total++
+		if len(hits) < size {
+			hits = append(hits, map[string]any{"_index": name, "_id": id, "_score": 1.0, "_source": i.documents[id]})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"took":      1,
+		"timed_out": false,
+		"hits":      map[string]any{"total": map[string]any{"value": total, "relation": "eq"}, "hits": hits},
+	})
+}
+
+// tokens returns the lowercase words of the text
+func tokens(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,178 @@
+package elasticsearchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// pipeline is an ingest pipeline; only its inference processors run
+type pipeline struct {
+	Description string      `json:"description,omitempty"`
+	Processors  []processor `json:"processors"`
+}
+
+type processor struct {
+	Inference *inferenceProcessor `json:"inference,omitempty"`
+}
+
+type inferenceProcessor struct {
+	ModelID     string `json:"model_id"`
+	InputOutput []struct {
+		InputField  string `json:"input_field"`
+		OutputField string `json:"output_field"`
+	} `json:"input_output"`
+}
+
+func (s *Server) routeIngest(mux *http.ServeMux) {
+	mux.HandleFunc("GET /_ingest/pipeline/{id}", s.getPipeline)
+	mux.HandleFunc("PUT /_ingest/pipeline/{id}", s.putPipeline)
+	mux.HandleFunc("DELETE /_ingest/pipeline/{id}", s.deletePipeline)
+	index := s.requireIndexPrivilege("index", "indices:data/write/index", s.indexDocument)
+	mux.HandleFunc("PUT /{index}/_doc/{id}", index)
+	mux.HandleFunc("POST /{index}/_doc/{id}", index)
+	mux.HandleFunc("POST /{index}/_doc", index)
+}
+
+func (s *Server) getPipeline(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pipelines[id]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{id: p})
+}
+
+// putPipeline accepts pipelines whose processors are all inference processors with an input_output
+func (s *Server) putPipeline(w http.ResponseWriter, r *http.Request) {
+	var p pipeline
+	if !readJSON(w, r, &p) {
+		return
+	}
+	for _, proc := range p.Processors {
+		if proc.Inference == nil || proc.Inference.ModelID == "" || len(proc.Inference.InputOutput) == 0 {
+			writeError(w, http.StatusBadRequest, "parse_exception", "elasticsearchtest supports inference processors with a model_id and an input_output only")
+			return
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipelines[r.PathValue("id")] = &p
+	writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+}
+
+func (s *Server) deletePipeline(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pipelines[id]; !ok {
+		writeError(w, http.StatusNotFound, "resource_not_found_exception", fmt.Sprintf("pipeline [%s] is missing", id))
+		return
+	}
+	delete(s.pipelines, id)
+	writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+}
+
+// indexDocument indexes a document, through the pipeline of the pipeline parameter when there is one
+func (s *Server) indexDocument(w http.ResponseWriter, r *http.Request) {
+	var source map[string]any
+	if !readJSON(w, r, &source) {
+		return
+	}
+	name, id := r.PathValue("index"), r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pipelineID := r.URL.Query().Get("pipeline"); pipelineID != "" {
+		p, ok := s.pipelines[pipelineID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "illegal_argument_exception", fmt.Sprintf("pipeline with id [%s] does not exist", pipelineID))
+			return
+		}
+		for _, proc := range p.Processors {
+			if err := s.infer(proc.Inference.ModelID); err != nil {
+				writeError(w, http.StatusConflict, "status_exception", err.Error())
+				return
+			}
+			for _, field := range proc.Inference.InputOutput {
+				text, ok := source[field.InputField].(string)
+				if !ok {
+					writeError(w, http.StatusBadRequest, "illegal_argument_exception", fmt.Sprintf("field [%s] not present as part of path [%s]", field.InputField, field.InputField))
+					return
+				}
+				source[field.OutputField] = expand(text)
+			}
+		}
+	}
+	status, result := s.apply("index", name, &id, source)
+	if status >= 300 {
+		writeError(w, status, result, fmt.Sprintf("[%s]: %s", id, result))
+		return
+	}
+	writeJSON(w, status, map[string]any{"_index": name, "_id": id, "result": result})
+}
+
+// infer returns an error unless the ID is a started deployment, or a model with a started deployment of the same ID
+func (s *Server) infer(id string) error {
+	for _, m := range s.models {
+		if d, ok := m.deployments[id]; ok && d.state == "started" {
+			return nil
+		}
+	}
+	return fmt.Errorf("Trained model deployment [%s] is not allocated to any nodes", id)
+}
+
+// expand stands in for ELSER: it expands the text into its lowercase words, each weighted by its length
+func expand(text string) map[string]any {
+	weights := map[string]any{}
+	for word := range tokens(text) {
+		weights[word] = float64(len(word)) / 10
+	}
+	return weights
+}
+
+// sparseQuery returns the field and the text of a sparse_vector or text_expansion query, with the inference ID or
+// model ID it expands the text with
+func sparseQuery(query map[string]map[string]any) (field string, id string, text string, err error) {
+	if q, ok := query["sparse_vector"]; ok {
+		field, _ = q["field"].(string)
+		id, _ = q["inference_id"].(string)
+		text, _ = q["query"].(string)
+		if field == "" || id == "" || text == "" {
+			return "", "", "", fmt.Errorf("elasticsearchtest supports sparse_vector queries with a field, an inference_id and a query only")
+		}
+		return field, id, text, nil
+	}
+	for f, value := range query["text_expansion"] {
+		var q struct {
+			ModelID   string `json:"model_id"`
+			ModelText string `json:"model_text"`
+		}
+		src, _ := json.Marshal(value)
+		if json.Unmarshal(src, &q) != nil || q.ModelID == "" || q.ModelText == "" {
+			break
+		}
+		return f, q.ModelID, q.ModelText, nil
+	}
+	return "", "", "", fmt.Errorf("[text_expansion] requires a model_id and a model_text")
+}
+
+// sparseMatch returns whether the expanded field of the document shares a token with the expanded text
+func sparseMatch(field string, text string) func(map[string]any) bool {
+	query := expand(text)
+	return func(source map[string]any) bool {
+		weights, ok := source[field].(map[string]any)
+		if !ok {
+			return false
+		}
+		for token := range weights {
+			if _, ok := query[strings.ToLower(token)]; ok {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,227 @@
+package elasticsearchtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// langIdentModel is the built-in model that every cluster has
+const langIdentModel = "lang_ident_model_1"
+
+// model is a trained model and its deployments by ID
+type model struct {
+	id        string
+	createdBy string
+	// definitionPolls is the number of definition status reads left before the model is fully defined
+	definitionPolls int
+	deployments     map[string]*deployment
+}
+
+// deployment is a trained model deployment
+type deployment struct {
+	state string
+	// startPolls is the number of stats reads left before a starting deployment is started
+	startPolls int
+}
+
+// AddTrainedModel adds a fully defined model created by a user (api_user), as if it were installed earlier.
+func (s *Server) AddTrainedModel(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models[id] = &model{id: id, createdBy: "api_user"}
+}
+
+// TrainedModels returns the IDs of the trained models, sorted.
+func (s *Server) TrainedModels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.models))
+	for id := range s.models {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// DeploymentState returns the state of the deployment of the model, "" when there is no such deployment.
+func (s *Server) DeploymentState(modelID string, deploymentID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.models[modelID]; ok && m.deployments[deploymentID] != nil {
+		return m.deployments[deploymentID].state
+	}
+	return ""
+}
+
+func (s *Server) routeTrainedModels(mux *http.ServeMux) {
+	mux.HandleFunc("GET /_ml/trained_models", s.getTrainedModels)
+	mux.HandleFunc("GET /_ml/trained_models/{id}", s.getTrainedModels)
+	mux.HandleFunc("PUT /_ml/trained_models/{id}", s.putTrainedModel)
+	mux.HandleFunc("DELETE /_ml/trained_models/{id}", s.deleteTrainedModel)
+	mux.HandleFunc("GET /_ml/trained_models/{id}/_stats", s.trainedModelStats)
+	mux.HandleFunc("POST /_ml/trained_models/{id}/deployment/_start", s.startDeployment)
+}
+
+// modelsOf returns the models of the path, all of them when it has no ID, sorted by ID, or answers 404 and returns
+// false
+func (s *Server) modelsOf(w http.ResponseWriter, r *http.Request) ([]*model, bool) {
+	id := r.PathValue("id")
+	if id == "" || id == "_all" || id == "*" {
+		models := make([]*model, 0, len(s.models))
+		for _, m := range s.models {
+			models = append(models, m)
+		}
+		sort.Slice(models, func(i, j int) bool { return models[i].id < models[j].id })
+		return models, true
+	}
+	m, ok := s.models[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "resource_not_found_exception", fmt.Sprintf("No known trained model with model_id [%s]", id))
+		return nil, false
+	}
+	return []*model{m}, true
+}
+
+// getTrainedModels lists the model configurations. With include=definition_status, every read of a model that is
+// not fully defined yet brings it closer to being fully defined.
+func (s *Server) getTrainedModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models, ok := s.modelsOf(w, r)
+	if !ok {
+		return
+	}
+	definitionStatus := r.URL.Query().Get("include") == "definition_status"
+	configs := []any{}
+	for _, m := range models {
+		config := map[string]any{"model_id": m.id, "created_by": m.createdBy, "model_type": "pytorch"}
+		if definitionStatus {
+			config["fully_defined"] = m.definitionPolls == 0
+			if m.definitionPolls > 0 {
+				m.definitionPolls--
+			}
+		}
+		configs = append(configs, config)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(configs), "trained_model_configs": configs})
+}
+
+func (s *Server) putTrainedModel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body struct {
+		Input struct {
+			FieldNames []string `json:"field_names"`
+		} `json:"input"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if len(body.Input.FieldNames) == 0 {
+		writeError(w, http.StatusBadRequest, "action_request_validation_exception", "Validation Failed: 1: [input] must not be null;")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.models[id]; ok {
+		writeError(w, http.StatusBadRequest, "resource_already_exists_exception", fmt.Sprintf("Trained machine learning model [%s] already exists", id))
+		return
+	}
+	s.models[id] = &model{id: id, createdBy: "api_user", definitionPolls: s.opts.ModelDefinitionPolls}
+	writeJSON(w, http.StatusOK, map[string]any{"model_id": id, "created_by": "api_user", "input": body.Input})
+}
+
+// deleteTrainedModel refuses to delete a deployed model unless force is set, and the built-in model
+func (s *Server) deleteTrainedModel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models, ok := s.modelsOf(w, r)
+	if !ok {
+		return
+	}
+	m := models[0]
+	switch {
+	case m.createdBy == "_xpack":
+		writeError(w, http.StatusBadRequest, "status_exception", fmt.Sprintf("Unable to delete model [%s]", m.id))
+	case len(m.deployments) > 0 && r.URL.Query().Get("force") != "true":
+		writeError(w, http.StatusConflict, "status_exception", fmt.Sprintf("Cannot delete model [%s] as it is currently deployed", m.id))
+	default:
+		delete(s.models, m.id)
+		writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true})
+	}
+}
+
+// trainedModelStats reports an entry per model and an entry per deployment. Every read of a starting deployment
+// brings it closer to being started.
+func (s *Server) trainedModelStats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models, ok := s.modelsOf(w, r)
+	if !ok {
+		return
+	}
+	stats := []any{}
+	for _, m := range models {
+		stats = append(stats, map[string]any{"model_id": m.id, "deployment_stats_count": len(m.deployments)})
+		ids := make([]string, 0, len(m.deployments))
+		for id := range m.deployments {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			d := m.deployments[id]
+			allocation := "starting"
+			if d.state == "started" {
+				allocation = "fully_allocated"
+			}
+			stats = append(stats, map[string]any{
+				"model_id": m.id,
+				"deployment_stats": map[string]any{
+					"deployment_id":     id,
+					"model_id":          m.id,
+					"state":             d.state,
+					"allocation_status": map[string]any{"state": allocation, "allocation_count": 1, "target_allocation_count": 1},
+				},
+			})
+			if d.state == "starting" {
+				if d.startPolls--; d.startPolls <= 0 {
+					d.state = "started"
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(stats), "trained_model_stats": stats})
+}
+
+// startDeployment starts a deployment, which is started at once unless wait_for is starting, and then starts
+// after DeploymentStartPolls stats reads
+func (s *Server) startDeployment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	models, ok := s.modelsOf(w, r)
+	if !ok {
+		return
+	}
+	m := models[0]
+	deploymentID := r.URL.Query().Get("deployment_id")
+	if deploymentID == "" {
+		deploymentID = m.id
+	}
+	switch {
+	case m.definitionPolls > 0:
+		writeError(w, http.StatusBadRequest, "status_exception", fmt.Sprintf("Model definition truncated. Unable to deserialize trained model definition [%s]", m.id))
+		return
+	case m.deployments[deploymentID] != nil:
+		writeError(w, http.StatusConflict, "status_exception", fmt.Sprintf("Could not start model deployment because an existing deployment with the same id [%s] exist", deploymentID))
+		return
+	}
+	d := &deployment{state: "started"}
+	if r.URL.Query().Get("wait_for") == "starting" && s.opts.DeploymentStartPolls > 0 {
+		d = &deployment{state: "starting", startPolls: s.opts.DeploymentStartPolls}
+	}
+	if m.deployments == nil {
+		m.deployments = map[string]*deployment{}
+	}
+	m.deployments[deploymentID] = d
+	writeJSON(w, http.StatusOK, map[string]any{"assignment": map[string]any{"task_parameters": map[string]any{"model_id": m.id, "deployment_id": deploymentID}, "assignment_state": d.state}})
+}
@@ -0,0 +1,153 @@
+package elasticsearchtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// user is a native user and its roles
+type user struct {
+	password string
+	roles    []string
+}
+
+// rolePrivileges are the index privileges of the roles of the fake. Users of Options.Users are superusers.
+var rolePrivileges = map[string][]string{
+	"superuser": {"all"},
+	"editor":    {"read", "view_index_metadata", "write", "index", "create", "delete", "create_index", "delete_index"},
+	"viewer":    {"read", "view_index_metadata"},
+}
+
+type usernameKey struct{}
+
+// AddUser adds a user with the roles: superuser, editor, which reads and writes indices, or viewer, which reads
+// them. The users of Options.Users are superusers.
+func (s *Server) AddUser(username string, password string, roles ...string) {
+	for _, role := range roles {
+		if _, ok := rolePrivileges[role]; !ok {
+			panic(fmt.Sprintf("elasticsearchtest: unknown role %s", role))
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = &user{password: password, roles: roles}
+}
+
+// authenticate returns the user of the request, or nil when its basic authentication is missing or wrong
+func (s *Server) authenticate(r *http.Request) (string, *user) {
+	username, password, ok := r.BasicAuth()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, known := s.users[username]
+	if !ok || !known || password != u.password {
+		return username, nil
+	}
+	return username, u
+}
+
+// granted reports whether one of the roles grants the index privilege
+func granted(roles []string, privilege string) bool {
+	for _, role := range roles {
+		if slices.Contains(rolePrivileges[role], "all") || slices.Contains(rolePrivileges[role], privilege) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireIndexPrivilege answers the requests of users without the index privilege with 403, as the cluster does
+func (s *Server) requireIndexPrivilege(privilege string, action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.Context().Value(usernameKey{}).(string)
+		s.mu.Lock()
+		roles := s.users[username].roles
+		s.mu.Unlock()
+		if !granted(roles, privilege) {
+			writeError(w, http.StatusForbidden, "security_exception", fmt.Sprintf("action [%s] is unauthorized for user [%s] with effective roles [%s]", action, username, joinRoles(roles)))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func joinRoles(roles []string) string {
+	sorted := slices.Clone(roles)
+	slices.Sort(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func (s *Server) routeSecurity(mux *http.ServeMux) {
+	mux.HandleFunc("GET /_security/_authenticate", s.authenticateUser)
+	mux.HandleFunc("GET /_security/user/_has_privileges", s.hasPrivileges)
+	mux.HandleFunc("POST /_security/user/_has_privileges", s.hasPrivileges)
+}
+
+func (s *Server) authenticateUser(w http.ResponseWriter, r *http.Request) {
+	username := r.Context().Value(usernameKey{}).(string)
+	s.mu.Lock()
+	roles := append([]string{}, s.users[username].roles...)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"username":            username,
+		"roles":               roles,
+		"enabled":             true,
+		"authentication_type": "realm",
+		"authentication_realm": map[string]any{
+			"name": "default_native",
+			"type": "native",
+		},
+	})
+}
+
+// hasPrivileges answers for the index privileges of the request, on every index alike; cluster privileges are
+// granted to superusers only
+func (s *Server) hasPrivileges(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Cluster []string `json:"cluster"`
+		Index   []struct {
+			Names      []string `json:"names"`
+			Privileges []string `json:"privileges"`
+		} `json:"index"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	username := r.Context().Value(usernameKey{}).(string)
+	s.mu.Lock()
+	roles := s.users[username].roles
+	s.mu.Unlock()
+
+	all := true
+	cluster := map[string]bool{}
+	for _, privilege := range body.Cluster {
+		cluster[privilege] = slices.Contains(roles, "superuser")
+		all = all && cluster[privilege]
+	}
+	index := map[string]map[string]bool{}
+	for _, request := range body.Index {
+		for _, name := range request.Names {
+			if index[name] == nil {
+				index[name] = map[string]bool{}
+			}
+			for _, privilege := range request.Privileges {
+				index[name][privilege] = granted(roles, privilege)
+				all = all && index[name][privilege]
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"username":          username,
+		"has_all_requested": all,
+		"cluster":           cluster,
+		"index":             index,
+		"application":       map[string]any{},
+	})
+}
+
+// withUser adds the username of an authenticated request to its context
+func withUser(r *http.Request, username string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), usernameKey{}, username))
+}
@@ -0,0 +1,341 @@
+// Package elasticsearchtest provides an in-memory Elasticsearch server for tests. It serves the REST endpoints that
+// the tests and tools in this repository use over TLS, with a certificate signed by its own CA and basic
+// authentication, and can inject failures and latency into chosen requests.
+package elasticsearchtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+)
+
+// Options configure a Server.
+type Options struct {
+	// Version is the version the cluster reports, 8.19.3 when empty
+	Version string
+	// Users maps the username of each superuser to its password, AddUser adds users with other roles
+	Users map[string]string
+	// ModelDefinitionPolls is the number of times a trained model reports that it is not fully defined after it is
+	// installed, before it reports that it is
+	ModelDefinitionPolls int
+	// DeploymentStartPolls is the number of times the stats report a deployment that is started with
+	// wait_for=starting as starting, before they report it as started
+	DeploymentStartPolls int
+	// Handler answers the authenticated requests instead of the in-memory cluster, when not nil, for responses the
+	// cluster does not produce. Faults and the recorded requests still apply.
+	Handler http.Handler
+}
+
+// Fault changes the responses to the requests it matches.
+type Fault struct {
+	// Method matches the request method, any method when empty
+	Method string
+	// Path matches the request path, such as /_ml/trained_models/.elser_model_2
+	Path string
+	// Status answers with this status code and an error document instead of serving the request, when not zero
+	Status int
+	// Latency delays the response
+	Latency time.Duration
+	// Times is the number of requests the fault applies to, every request when zero
+	Times int
+}
+
+// Server is an in-memory Elasticsearch cluster.
+type Server struct {
+	// URL is the base URL of the server, https://127.0.0.1:<port>
+	URL string
+
+	opts       Options
+	users      map[string]*user
+	server     *httptest.Server
+	caPEM      []byte
+	mu         sync.Mutex
+	status     string
+	settings   map[string]map[string]any
+	indices    map[string]*index
+	models     map[string]*model
+	pipelines  map[string]*pipeline
+	faults     []*Fault
+	requests   []string
+	documentID int
+}
+
+// NewServer starts a server. Close it when the test is done.
+func NewServer(opts Options) *Server {
+	if opts.Version == "" {
+		opts.Version = "8.19.3"
+	}
+	s := &Server{
+		opts:      opts,
+		users:     map[string]*user{},
+		status:    "green",
+		settings:  map[string]map[string]any{"persistent": {}, "transient": {}},
+		indices:   map[string]*index{},
+		models:    map[string]*model{langIdentModel: {id: langIdentModel, createdBy: "_xpack"}},
+		pipelines: map[string]*pipeline{},
+	}
+	for username, password := range opts.Users {
+		s.users[username] = &user{password: password, roles: []string{"superuser"}}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.info)
+	mux.HandleFunc("GET /_cluster/health", s.health)
+	mux.HandleFunc("GET /_cluster/settings", s.getSettings)
+	mux.HandleFunc("PUT /_cluster/settings", s.putSettings)
+	mux.HandleFunc("POST /_bulk", s.requireIndexPrivilege("write", "indices:data/write/bulk", s.bulk))
+	mux.HandleFunc("PUT /{index}", s.requireIndexPrivilege("create_index", "indices:admin/create", s.createIndex))
+	mux.HandleFunc("DELETE /{index}", s.requireIndexPrivilege("delete_index", "indices:admin/delete", s.deleteIndex))
+	mux.HandleFunc("POST /{index}/_bulk", s.requireIndexPrivilege("write", "indices:data/write/bulk", s.bulk))
+	mux.HandleFunc("GET /{index}/_search", s.search)
+	mux.HandleFunc("POST /{index}/_search", s.search)
+	s.routeTrainedModels(mux)
+	s.routeIngest(mux)
+	s.routeSecurity(mux)
+
+	ca, leaf, err := certificates()
+	if err != nil {
+		panic(fmt.Sprintf("elasticsearchtest: %v", err))
+	}
+	s.caPEM = ca
+	var routes http.Handler = mux
+	if opts.Handler != nil {
+		routes = opts.Handler
+	}
+	s.server = httptest.NewUnstartedServer(s.handler(routes))
+	// the handshakes of clients that do not trust the CA fail, on purpose
+	s.server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	s.server.TLS = &tls.Config{Certificates: []tls.Certificate{leaf}, MinVersion: tls.VersionTLS12}
+	s.server.StartTLS()
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// CertificateBase64 returns the CA certificate of the server as the module outputs certificate_base64.
+func (s *Server) CertificateBase64() string {
+	return base64.StdEncoding.EncodeToString(s.caPEM)
+}
+
+// Credentials returns credentials to connect to the server as the user, trusting its CA.
+func (s *Server) Credentials(username string) credentials.Credentials {
+	host, port, _ := net.SplitHostPort(s.server.Listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return credentials.Credentials{
+		Name:              username,
+		Username:          username,
+		Password:          s.password(username),
+		Hostname:          host,
+		Port:              portNumber,
+		CertificateBase64: s.CertificateBase64(),
+	}
+}
+
+func (s *Server) password(username string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[username]; ok {
+		return u.password
+	}
+	return ""
+}
+
+// SetStatus sets the status the cluster health reports: green, yellow or red.
+func (s *Server) SetStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Inject adds a fault. Faults are matched in the order they are added, the first one that matches applies.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Requests returns every authenticated request so far as "<method> <path and query>".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// handler authenticates the request, records it and applies the first matching fault
+func (s *Server) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, u := s.authenticate(r)
+		if u == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="security" charset="UTF-8"`)
+			writeError(w, http.StatusUnauthorized, "security_exception", fmt.Sprintf("unable to authenticate user [%s] for REST request [%s]", username, r.URL.Path))
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+		var fault Fault
+		for i, f := range s.faults {
+			if (f.Method == "" || f.Method == r.Method) && f.Path == r.URL.Path {
+				fault = *f
+				if f.Times > 0 {
+					if f.Times--; f.Times == 0 {
+						s.faults = append(s.faults[:i], s.faults[i+1:]...)
+					}
+				}
+				break
+			}
+		}
+		s.mu.Unlock()
+
+		if fault.Latency > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(fault.Latency):
+			}
+		}
+		if fault.Status != 0 {
+			writeError(w, fault.Status, "injected_fault", fmt.Sprintf("injected %d for %s %s", fault.Status, r.Method, r.URL.Path))
+			return
+		}
+		next.ServeHTTP(w, withUser(r, username))
+	})
+}
+
+func (s *Server) info(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name":         "instance-0000000000",
+		"cluster_name": "elasticsearchtest",
+		"version":      map[string]any{"number": s.opts.Version, "build_flavor": "default"},
+		"tagline":      "You Know, for Search",
+	})
+}
+
+var statusOrder = map[string]int{"green": 0, "yellow": 1, "red": 2}
+
+// health answers 408 with timed_out set when the status is worse than wait_for_status, as the cluster does when the
+// status is not reached before the timeout
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+	code, timedOut := http.StatusOK, false
+	if want := r.URL.Query().Get("wait_for_status"); want != "" && statusOrder[status] > statusOrder[want] {
+		code, timedOut = http.StatusRequestTimeout, true
+	}
+	writeJSON(w, code, map[string]any{"cluster_name": "elasticsearchtest", "status": status, "timed_out": timedOut, "number_of_nodes": 3})
+}
+
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.settings)
+}
+
+// putSettings merges the persistent and transient settings, a null value removes a setting
+func (s *Server) putSettings(w http.ResponseWriter, r *http.Request) {
+	var body map[string]map[string]any
+	if !readJSON(w, r, &body) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, scope := range []string{"persistent", "transient"} {
+		for key, value := range body[scope] {
+			if value == nil {
+				delete(s.settings[scope], key)
+			} else {
+				s.settings[scope][key] = value
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"acknowledged": true, "persistent": body["persistent"], "transient": body["transient"]})
+}
+
+// certificates returns a new CA certificate in PEM and a server certificate for 127.0.0.1 and localhost signed by it
+func certificates() ([]byte, tls.Certificate, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "elasticsearchtest CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	return caPEM, tls.Certificate{Certificate: [][]byte{leafDER}, PrivateKey: key}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error document in the format of Elasticsearch
+func writeError(w http.ResponseWriter, status int, errorType string, reason string) {
+	cause := map[string]any{"type": errorType, "reason": reason}
+	writeJSON(w, status, map[string]any{
+		"error":  map[string]any{"root_cause": []any{cause}, "type": errorType, "reason": reason},
+		"status": status,
+	})
+}
+
+// readJSON decodes the request body, or answers 400 and returns false
+func readJSON(w http.ResponseWriter, r *http.Request, out any) bool {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return false
+	}
+	return true
+}
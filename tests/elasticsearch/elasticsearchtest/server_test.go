@@ -0,0 +1,312 @@
+package elasticsearchtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+)
+
+var users = map[string]string{"admin": "secret"} // pragma: allowlist secret
+
+// start starts a server and returns it with a client for admin
+func start(t *testing.T, opts Options) (*Server, *elasticsearch.Client) {
+	t.Helper()
+	opts.Users = users
+	server := NewServer(opts)
+	t.Cleanup(server.Close)
+	client, err := elasticsearch.New(server.Credentials("admin"))
+	require.NoError(t, err)
+	return server, client
+}
+
+func statusCode(t *testing.T, err error) int {
+	t.Helper()
+	var e *elasticsearch.Error
+	require.ErrorAs(t, err, &e)
+	return e.StatusCode
+}
+
+func TestCertificateAndAuthentication(t *testing.T) {
+	server, client := start(t, Options{Version: "8.15.2"})
+	info, err := client.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "8.15.2", info.Version.Number)
+
+	wrongPassword := server.Credentials("admin")
+	wrongPassword.Password = "wrong" // pragma: allowlist secret
+	client, err = elasticsearch.New(wrongPassword)
+	require.NoError(t, err)
+	_, err = client.Info(context.Background())
+	assert.Equal(t, http.StatusUnauthorized, statusCode(t, err))
+	assert.ErrorContains(t, err, "unable to authenticate user [admin]")
+
+	other := NewServer(Options{Users: users})
+	defer other.Close()
+	assert.NotEqual(t, server.CertificateBase64(), other.CertificateBase64(), "every server has its own CA")
+	untrusted := server.Credentials("admin")
+	untrusted.CertificateBase64 = other.CertificateBase64()
+	client, err = elasticsearch.New(untrusted)
+	require.NoError(t, err)
+	_, err = client.Info(context.Background())
+	assert.ErrorContains(t, err, "certificate signed by unknown authority")
+
+	assert.Equal(t, []string{"GET /"}, server.Requests(), "unauthenticated requests are not recorded")
+}
+
+func TestHealthAndSettings(t *testing.T) {
+	server, client := start(t, Options{})
+	ctx := context.Background()
+
+	health, err := client.Health(ctx, "green", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "green", health.Status)
+	server.SetStatus("yellow")
+	health, err = client.Health(ctx, "green", time.Second)
+	require.NoError(t, err)
+	assert.True(t, health.TimedOut)
+	health, err = client.Health(ctx, "yellow", time.Second)
+	require.NoError(t, err)
+	assert.False(t, health.TimedOut)
+
+	require.NoError(t, client.Do(ctx, http.MethodPut, "/_cluster/settings", map[string]any{"persistent": map[string]any{"action.auto_create_index": "false", "cluster.max_shards_per_node": 2000}}, nil))
+	require.NoError(t, client.Do(ctx, http.MethodPut, "/_cluster/settings", map[string]any{"persistent": map[string]any{"cluster.max_shards_per_node": nil}}, nil))
+	var settings map[string]map[string]any
+	require.NoError(t, client.Do(ctx, http.MethodGet, "/_cluster/settings", nil, &settings))
+	assert.Equal(t, map[string]map[string]any{"persistent": {"action.auto_create_index": "false"}, "transient": {}}, settings)
+}
+
+func TestIndices(t *testing.T) {
+	server, client := start(t, Options{})
+	ctx := context.Background()
+
+	require.NoError(t, client.Do(ctx, http.MethodPut, "/logs", map[string]any{"mappings": map[string]any{"properties": map[string]any{"message": map[string]any{"type": "text"}}}}, nil))
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodPut, "/logs", nil, nil)), "the index exists")
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodPut, "/Logs", nil, nil)), "index names are lowercase")
+
+	var bulk struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+		} `json:"items"`
+	}
+	require.NoError(t, client.Do(ctx, http.MethodPost, "/logs/_bulk?refresh=wait_for", elasticsearch.NDJSON{
+		map[string]any{"index": map[string]any{"_id": "a"}}, map[string]any{"message": "Smoke test one"},
+		map[string]any{"index": map[string]any{}}, map[string]any{"message": "another smoke"},
+		map[string]any{"create": map[string]any{"_id": "a"}}, map[string]any{"message": "duplicate"},
+		map[string]any{"index": map[string]any{"_index": "other"}}, map[string]any{"message": "elsewhere"},
+		map[string]any{"index": map[string]any{"_id": "c"}}, map[string]any{"message": "unrelated"},
+	}, &bulk))
+	assert.True(t, bulk.Errors)
+	var statuses []int
+	for _, item := range bulk.Items {
+		for _, result := range item {
+			statuses = append(statuses, result.Status)
+		}
+	}
+	assert.Equal(t, []int{201, 201, 409, 201, 201}, statuses)
+	documents, ok := server.Documents("logs")
+	assert.True(t, ok)
+	assert.Equal(t, 3, documents)
+	documents, ok = server.Documents("other")
+	assert.True(t, ok, "bulk creates missing indices")
+	assert.Equal(t, 1, documents)
+
+	var found struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	require.NoError(t, client.Do(ctx, http.MethodPost, "/logs/_search", map[string]any{"query": map[string]any{"match": map[string]any{"message": "SMOKE"}}, "size": 1}, &found))
+	assert.Equal(t, 2, found.Hits.Total.Value)
+	require.Len(t, found.Hits.Hits, 1)
+	assert.Equal(t, "1", found.Hits.Hits[0].ID, "hits are sorted by ID")
+	require.NoError(t, client.Do(ctx, http.MethodGet, "/logs/_search", nil, &found))
+	assert.Equal(t, 3, found.Hits.Total.Value)
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodPost, "/logs/_search", map[string]any{"query": map[string]any{"fuzzy": map[string]any{"message": "smok"}}}, nil)))
+
+	require.NoError(t, client.Do(ctx, http.MethodDelete, "/logs", nil, nil))
+	_, ok = server.Documents("logs")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusNotFound, statusCode(t, client.Do(ctx, http.MethodDelete, "/logs", nil, nil)))
+	assert.Equal(t, http.StatusNotFound, statusCode(t, client.Do(ctx, http.MethodGet, "/logs/_search", nil, nil)))
+}
+
+type modelConfigs struct {
+	Configs []struct {
+		ModelID      string `json:"model_id"`
+		CreatedBy    string `json:"created_by"`
+		FullyDefined bool   `json:"fully_defined"`
+	} `json:"trained_model_configs"`
+}
+
+type modelStats struct {
+	Stats []struct {
+		DeploymentStats *struct {
+			DeploymentID     string `json:"deployment_id"`
+			State            string `json:"state"`
+			AllocationStatus struct {
+				State string `json:"state"`
+			} `json:"allocation_status"`
+		} `json:"deployment_stats"`
+	} `json:"trained_model_stats"`
+}
+
+func TestTrainedModels(t *testing.T) {
+	server, client := start(t, Options{ModelDefinitionPolls: 2, DeploymentStartPolls: 2})
+	server.AddTrainedModel(".elser_model_1")
+	ctx := context.Background()
+	path := "/_ml/trained_models/.elser_model_2"
+
+	var configs modelConfigs
+	require.NoError(t, client.Do(ctx, http.MethodGet, "/_ml/trained_models", nil, &configs))
+	require.Len(t, configs.Configs, 2)
+	assert.Equal(t, ".elser_model_1", configs.Configs[0].ModelID)
+	assert.Equal(t, "api_user", configs.Configs[0].CreatedBy)
+	assert.Equal(t, "_xpack", configs.Configs[1].CreatedBy)
+
+	assert.Equal(t, http.StatusNotFound, statusCode(t, client.Do(ctx, http.MethodGet, path, nil, nil)))
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodPut, path, map[string]any{}, nil)), "the input is required")
+	input := map[string]any{"input": map[string]any{"field_names": []string{"text_field"}}}
+	require.NoError(t, client.Do(ctx, http.MethodPut, path+"?wait_for_completion=true", input, nil))
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodPut, path, input, nil)), "the model exists")
+
+	var defined []bool
+	for range 3 {
+		require.NoError(t, client.Do(ctx, http.MethodGet, path+"?include=definition_status", nil, &configs))
+		defined = append(defined, configs.Configs[0].FullyDefined)
+	}
+	assert.Equal(t, []bool{false, false, true}, defined)
+
+	start := path + "/deployment/_start?deployment_id=for_search&wait_for=starting"
+	require.NoError(t, client.Do(ctx, http.MethodPost, start, nil, nil))
+	assert.Equal(t, http.StatusConflict, statusCode(t, client.Do(ctx, http.MethodPost, start, nil, nil)))
+	var states []string
+	for range 3 {
+		var stats modelStats
+		require.NoError(t, client.Do(ctx, http.MethodGet, path+"/_stats", nil, &stats))
+		require.Len(t, stats.Stats, 2)
+		d := stats.Stats[1].DeploymentStats
+		assert.Equal(t, "for_search", d.DeploymentID)
+		states = append(states, d.State+"/"+d.AllocationStatus.State)
+	}
+	assert.Equal(t, []string{"starting/starting", "starting/starting", "started/fully_allocated"}, states)
+	assert.Equal(t, "started", server.DeploymentState(".elser_model_2", "for_search"))
+
+	assert.Equal(t, http.StatusConflict, statusCode(t, client.Do(ctx, http.MethodDelete, path, nil, nil)), "the model is deployed")
+	assert.Equal(t, http.StatusBadRequest, statusCode(t, client.Do(ctx, http.MethodDelete, "/_ml/trained_models/lang_ident_model_1", nil, nil)))
+	require.NoError(t, client.Do(ctx, http.MethodDelete, path+"?force=true", nil, nil))
+	require.NoError(t, client.Do(ctx, http.MethodDelete, "/_ml/trained_models/.elser_model_1", nil, nil))
+	assert.Equal(t, []string{"lang_ident_model_1"}, server.TrainedModels())
+}
+
+func TestInject(t *testing.T) {
+	server, client := start(t, Options{})
+	ctx := context.Background()
+
+	server.Inject(Fault{Method: http.MethodGet, Path: "/", Status: http.StatusServiceUnavailable, Times: 2})
+	for range 2 {
+		_, err := client.Info(ctx)
+		assert.Equal(t, http.StatusServiceUnavailable, statusCode(t, err))
+	}
+	_, err := client.Info(ctx)
+	require.NoError(t, err, "the fault applies twice")
+
+	server.Inject(Fault{Path: "/_cluster/health", Latency: time.Second})
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Health(timeout, "green", time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = client.Info(ctx)
+	require.NoError(t, err, "faults apply to their path only")
+
+	assert.Equal(t, []string{"GET /", "GET /", "GET /", "GET /_cluster/health?wait_for_status=green&timeout=1s", "GET /"}, server.Requests())
+}
+
+func TestHandler(t *testing.T) {
+	server, client := start(t, Options{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"version": {}}`))
+	})})
+	server.Inject(Fault{Path: "/", Status: http.StatusServiceUnavailable, Times: 1})
+	ctx := context.Background()
+
+	_, err := client.Info(ctx)
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode(t, err), "faults apply before the handler")
+	info, err := client.Info(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, info.Version.Number)
+
+	wrongPassword := server.Credentials("admin")
+	wrongPassword.Password = "wrong" // pragma: allowlist secret
+	other, err := elasticsearch.New(wrongPassword)
+	require.NoError(t, err)
+	_, err = other.Info(ctx)
+	assert.Equal(t, http.StatusUnauthorized, statusCode(t, err), "the handler only gets authenticated requests")
+	assert.Equal(t, []string{"GET /", "GET /"}, server.Requests())
+}
+
+func TestSecurity(t *testing.T) {
+	server, admin := start(t, Options{})
+	server.AddUser("viewer", "viewer-password", "viewer")                            // pragma: allowlist secret
+	server.AddUser("editor", "editor-password", "editor")                            // pragma: allowlist secret
+	assert.Panics(t, func() { server.AddUser("other", "other-password", "reader") }) // pragma: allowlist secret
+	ctx := context.Background()
+
+	clients := map[string]*elasticsearch.Client{"admin": admin}
+	for _, username := range []string{"viewer", "editor"} {
+		client, err := elasticsearch.New(server.Credentials(username))
+		require.NoError(t, err)
+		clients[username] = client
+	}
+
+	tests := []struct {
+		username  string
+		wantRoles []string
+		wantWrite bool
+	}{
+		{username: "admin", wantRoles: []string{"superuser"}, wantWrite: true},
+		{username: "viewer", wantRoles: []string{"viewer"}, wantWrite: false},
+		{username: "editor", wantRoles: []string{"editor"}, wantWrite: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.username, func(t *testing.T) {
+			client := clients[tc.username]
+			var authenticated struct {
+				Username string   `json:"username"`
+				Roles    []string `json:"roles"`
+			}
+			require.NoError(t, client.Do(ctx, http.MethodGet, "/_security/_authenticate", nil, &authenticated))
+			assert.Equal(t, tc.username, authenticated.Username)
+			assert.Equal(t, tc.wantRoles, authenticated.Roles)
+
+			var privileges struct {
+				HasAllRequested bool                       `json:"has_all_requested"`
+				Index           map[string]map[string]bool `json:"index"`
+			}
+			request := map[string]any{"index": []any{map[string]any{"names": []string{"logs"}, "privileges": []string{"read", "write"}}}}
+			require.NoError(t, client.Do(ctx, http.MethodPost, "/_security/user/_has_privileges", request, &privileges))
+			assert.Equal(t, map[string]map[string]bool{"logs": {"read": true, "write": tc.wantWrite}}, privileges.Index)
+			assert.Equal(t, tc.wantWrite, privileges.HasAllRequested)
+
+			err := client.Do(ctx, http.MethodPost, "/"+tc.username+"/_doc", map[string]any{"message": "hello"}, nil)
+			if tc.wantWrite {
+				require.NoError(t, err)
+			} else {
+				assert.Equal(t, http.StatusForbidden, statusCode(t, err))
+				assert.ErrorContains(t, err, "action [indices:data/write/index] is unauthorized for user [viewer] with effective roles [viewer]")
+			}
+		})
+	}
+}
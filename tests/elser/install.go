@@ -0,0 +1,125 @@
+// Package elser installs and starts the ELSER trained model on a deployed instance, as the put_vectordb_model.sh and
+// start_vectordb_model.sh scripts that the module runs do, with TLS verified against the CA certificate of the
+// instance, and verifies that the model expands text. The tests run the scripts and this package against the same
+// fake cluster, so that they do not drift apart.
+package elser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+)
+
+// DefaultBackoff retries like the scripts: four attempts, waiting 4, 8 and 16 seconds in between.
+var DefaultBackoff = elasticsearch.Backoff{Attempts: 4, Delay: 4 * time.Second}
+
+// StepError is a step that failed after its last attempt.
+type StepError struct {
+	// Step is "list", "delete" or "put"
+	Step string
+	// Model is the model of the step, empty for "list"
+	Model    string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	if e.Model == "" {
+		return fmt.Sprintf("%s trained models failed after %d attempts: %v", e.Step, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%s trained model %s failed after %d attempts: %v", e.Step, e.Model, e.Attempts, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Options configure Install.
+type Options struct {
+	// Backoff retries each request, DefaultBackoff when zero
+	Backoff elasticsearch.Backoff
+	// DryRun only lists the trained models and returns the plan, without deleting or installing anything
+	DryRun bool
+	// Logf logs the progress, nothing is logged when nil
+	Logf func(format string, args ...any)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Backoff.Attempts == 0 {
+		o.Backoff = DefaultBackoff
+	}
+	if o.Logf == nil {
+		o.Logf = func(string, ...any) {}
+	}
+	return o
+}
+
+// Plan is what Install does or, in a dry run, would do.
+type Plan struct {
+	// Delete are the models created by users (api_user) that are not the model to install
+	Delete []string
+	// Put is whether the model must be installed, false if it is already
+	Put bool
+}
+
+type trainedModels struct {
+	Configs []struct {
+		ModelID   string `json:"model_id"`
+		CreatedBy string `json:"created_by"`
+	} `json:"trained_model_configs"`
+}
+
+// Install makes model the only trained model created by users: it deletes the other user models and installs
+// model unless it is already there. The built-in models, such as the language identification model, are kept.
+func Install(ctx context.Context, client *elasticsearch.Client, model string, opts Options) (Plan, error) {
+	opts = opts.withDefaults()
+
+	var models trainedModels
+	attempts, err := opts.Backoff.Retry(ctx, func() error {
+		return client.Do(ctx, http.MethodGet, "/_ml/trained_models", nil, &models)
+	})
+	if err != nil {
+		return Plan{}, &StepError{Step: "list", Attempts: attempts, Err: err}
+	}
+
+	plan := Plan{Put: true}
+	for _, config := range models.Configs {
+		switch {
+		case config.CreatedBy != "api_user":
+		case config.ModelID == model:
+			plan.Put = false
+		default:
+			plan.Delete = append(plan.Delete, config.ModelID)
+		}
+	}
+	opts.Logf("trained models to delete: %v, install %s: %t", plan.Delete, model, plan.Put)
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	for _, stale := range plan.Delete {
+		attempts, err := opts.Backoff.Retry(ctx, func() error {
+			return client.Do(ctx, http.MethodDelete, "/_ml/trained_models/"+url.PathEscape(stale), nil, nil)
+		})
+		if err != nil {
+			return plan, &StepError{Step: "delete", Model: stale, Attempts: attempts, Err: err}
+		}
+		opts.Logf("deleted trained model %s", stale)
+	}
+
+	if plan.Put {
+		body := map[string]any{"input": map[string]any{"field_names": []string{"text_field"}}}
+		attempts, err := opts.Backoff.Retry(ctx, func() error {
+			return client.Do(ctx, http.MethodPut, "/_ml/trained_models/"+url.PathEscape(model)+"?wait_for_completion=true", body, nil)
+		})
+		if err != nil {
+			return plan, &StepError{Step: "put", Model: model, Attempts: attempts, Err: err}
+		}
+		opts.Logf("installed trained model %s", model)
+	}
+	return plan, nil
+}
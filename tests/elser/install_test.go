@@ -0,0 +1,122 @@
+package elser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+var fastBackoff = elasticsearch.Backoff{Attempts: 4, Delay: time.Millisecond}
+
+// serve starts a fake cluster with the options and returns it with a client for admin
+func serve(t *testing.T, opts elasticsearchtest.Options) (*elasticsearchtest.Server, *elasticsearch.Client) {
+	t.Helper()
+	opts.Users = map[string]string{"admin": "secret"} // pragma: allowlist secret
+	server := elasticsearchtest.NewServer(opts)
+	t.Cleanup(server.Close)
+	client, err := elasticsearch.New(server.Credentials("admin"))
+	require.NoError(t, err)
+	return server, client
+}
+
+const (
+	langIdent = "lang_ident_model_1"
+	putModel  = "/_ml/trained_models/.elser_model_2"
+)
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name string
+		// models are the user models installed before
+		models       []string
+		dryRun       bool
+		want         Plan
+		wantRequests []string
+		wantModels   []string
+	}{
+		{
+			name:         "model already present",
+			models:       []string{".elser_model_2"},
+			want:         Plan{},
+			wantRequests: []string{"GET /_ml/trained_models"},
+			wantModels:   []string{".elser_model_2", langIdent},
+		},
+		{
+			name:   "stale models deleted",
+			models: []string{".elser_model_1", "my-model"},
+			want:   Plan{Delete: []string{".elser_model_1", "my-model"}, Put: true},
+			wantRequests: []string{
+				"GET /_ml/trained_models",
+				"DELETE /_ml/trained_models/.elser_model_1",
+				"DELETE /_ml/trained_models/my-model",
+				"PUT /_ml/trained_models/.elser_model_2?wait_for_completion=true",
+			},
+			wantModels: []string{".elser_model_2", langIdent},
+		},
+		{
+			name:         "dry run",
+			models:       []string{".elser_model_1"},
+			dryRun:       true,
+			want:         Plan{Delete: []string{".elser_model_1"}, Put: true},
+			wantRequests: []string{"GET /_ml/trained_models"},
+			wantModels:   []string{".elser_model_1", langIdent},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := serve(t, elasticsearchtest.Options{})
+			for _, id := range tc.models {
+				server.AddTrainedModel(id)
+			}
+			plan, err := Install(context.Background(), client, ".elser_model_2", Options{Backoff: fastBackoff, DryRun: tc.dryRun})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, plan)
+			assert.Equal(t, tc.wantRequests, server.Requests())
+			assert.Equal(t, tc.wantModels, server.TrainedModels())
+		})
+	}
+}
+
+func TestInstallRetries(t *testing.T) {
+	server, client := serve(t, elasticsearchtest.Options{})
+	server.Inject(elasticsearchtest.Fault{Method: http.MethodPut, Path: putModel, Status: http.StatusServiceUnavailable, Times: 3})
+	_, err := Install(context.Background(), client, ".elser_model_2", Options{Backoff: fastBackoff})
+	require.NoError(t, err, "the fourth attempt succeeds")
+	assert.Len(t, server.Requests(), 5)
+	assert.Equal(t, []string{".elser_model_2", langIdent}, server.TrainedModels())
+}
+
+func TestInstallPutFailsFourTimes(t *testing.T) {
+	server, client := serve(t, elasticsearchtest.Options{})
+	server.Inject(elasticsearchtest.Fault{Method: http.MethodPut, Path: putModel, Status: http.StatusServiceUnavailable, Times: 4})
+	_, err := Install(context.Background(), client, ".elser_model_2", Options{Backoff: fastBackoff})
+
+	var step *StepError
+	require.True(t, errors.As(err, &step), err)
+	assert.Equal(t, "put", step.Step)
+	assert.Equal(t, ".elser_model_2", step.Model)
+	assert.Equal(t, 4, step.Attempts)
+	var response *elasticsearch.Error
+	require.True(t, errors.As(err, &response))
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Len(t, server.Requests(), 5)
+	assert.Equal(t, []string{langIdent}, server.TrainedModels())
+}
+
+func TestInstallDoesNotRetryPermanentErrors(t *testing.T) {
+	server, client := serve(t, elasticsearchtest.Options{})
+	server.Inject(elasticsearchtest.Fault{Path: "/_ml/trained_models", Status: http.StatusForbidden})
+
+	_, err := Install(context.Background(), client, ".elser_model_2", Options{Backoff: fastBackoff})
+	assert.ErrorContains(t, err, "list trained models failed after 1 attempts: GET /_ml/trained_models: 403 Forbidden: ")
+	assert.Len(t, server.Requests(), 1)
+}
@@ -0,0 +1,239 @@
+package elser
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+// runScript runs a script of the module in /scripts against the server like the module does, trusting the
+// certificate, and returns its output
+func runScript(t *testing.T, script string, server *elasticsearchtest.Server, certificate string) (string, error) {
+	t.Helper()
+	for _, binary := range []string{"bash", "curl", "jq", "base64"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			t.Skipf("the script needs %s: %v", binary, err)
+		}
+	}
+	c := server.Credentials("admin")
+	cmd := exec.Command("bash", filepath.Join("..", "..", "scripts", script))
+	cmd.Env = append(os.Environ(),
+		"ES=https://"+c.Username+":"+c.Password+"@"+c.Hostname+":"+strconv.Itoa(c.Port),
+		"ES_CERTIFICATE_BASE64="+certificate,
+		"ELSER_MODEL_TYPE=.elser_model_2",
+		"ELSER_START_TIMEOUT=2",
+		"ELSER_POLL_INTERVAL=0.01",
+		"ELSER_RETRY_DELAY=0",
+	)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// untrustedCertificate returns a CA certificate that did not sign the certificate of any other server
+func untrustedCertificate() string {
+	other := elasticsearchtest.NewServer(elasticsearchtest.Options{})
+	defer other.Close()
+	return other.CertificateBase64()
+}
+
+// TestPutScript runs the script and Install against the same clusters, as they must agree
+func TestPutScript(t *testing.T) {
+	tests := []struct {
+		name string
+		// models are the user models installed before
+		models    []string
+		fault     *elasticsearchtest.Fault
+		untrusted bool
+		wantErr   bool
+		wantOut   string
+		// wantModels are the models after the script, with the built-in model
+		wantModels []string
+	}{
+		{
+			name:       "model already present",
+			models:     []string{".elser_model_2"},
+			wantOut:    "Model '.elser_model_2' already installed. Do not install it.",
+			wantModels: []string{".elser_model_2", langIdent},
+		},
+		{
+			name:       "stale models deleted",
+			models:     []string{".elser_model_1", "my-model"},
+			wantOut:    "Trained model '.elser_model_2' installed successfully.",
+			wantModels: []string{".elser_model_2", langIdent},
+		},
+		{
+			name:       "put fails three times",
+			fault:      &elasticsearchtest.Fault{Method: http.MethodPut, Path: "/_ml/trained_models/.elser_model_2", Status: http.StatusServiceUnavailable, Times: 3},
+			wantOut:    "Trained model '.elser_model_2' installed successfully.",
+			wantModels: []string{".elser_model_2", langIdent},
+		},
+		{
+			name:       "put fails four times",
+			fault:      &elasticsearchtest.Fault{Method: http.MethodPut, Path: "/_ml/trained_models/.elser_model_2", Status: http.StatusServiceUnavailable, Times: 4},
+			wantErr:    true,
+			wantOut:    "Failed to install the model '.elser_model_2'. HTTP status code: 503",
+			wantModels: []string{langIdent},
+		},
+		{
+			name:       "untrusted certificate",
+			models:     []string{".elser_model_1"},
+			untrusted:  true,
+			wantErr:    true,
+			wantModels: []string{".elser_model_1", langIdent},
+		},
+	}
+	for _, tc := range tests {
+		// cluster starts a cluster with the models and returns it with the certificate to trust
+		cluster := func(t *testing.T) (*elasticsearchtest.Server, string) {
+			server, _ := serve(t, elasticsearchtest.Options{})
+			for _, id := range tc.models {
+				server.AddTrainedModel(id)
+			}
+			if tc.fault != nil {
+				server.Inject(*tc.fault)
+			}
+			if tc.untrusted {
+				return server, untrustedCertificate()
+			}
+			return server, server.CertificateBase64()
+		}
+
+		t.Run(tc.name+"/script", func(t *testing.T) {
+			server, certificate := cluster(t)
+			out, err := runScript(t, "put_vectordb_model.sh", server, certificate)
+			if tc.wantErr {
+				assert.Error(t, err, out)
+			} else {
+				assert.NoError(t, err, out)
+			}
+			assert.Contains(t, out, tc.wantOut)
+			assert.Equal(t, tc.wantModels, server.TrainedModels())
+		})
+		t.Run(tc.name+"/Install", func(t *testing.T) {
+			server, certificate := cluster(t)
+			c := server.Credentials("admin")
+			c.CertificateBase64 = certificate
+			installer, err := elasticsearch.New(c)
+			require.NoError(t, err)
+			_, err = Install(context.Background(), installer, ".elser_model_2", Options{Backoff: fastBackoff})
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantModels, server.TrainedModels())
+		})
+	}
+}
+
+// TestStartScript runs the script and Start against the same clusters, as they must agree
+func TestStartScript(t *testing.T) {
+	input := map[string]any{"input": map[string]any{"field_names": []string{"text_field"}}}
+	path := "/_ml/trained_models/.elser_model_2"
+	tests := []struct {
+		name string
+		// started starts the deployment before the script runs
+		started bool
+		fault   *elasticsearchtest.Fault
+		// untrusted gives the script a CA certificate that did not sign the certificate of the server
+		untrusted bool
+		wantErr   bool
+		wantOut   string
+		wantState string
+	}{
+		{
+			name:      "starts the deployment once the model is defined",
+			wantOut:   "The deployment 'for_search' of the trained model '.elser_model_2' is started.",
+			wantState: "started",
+		},
+		{
+			name:      "deployment exists",
+			started:   true,
+			wantOut:   "already exists: started/fully_allocated",
+			wantState: "started",
+		},
+		{
+			name:      "stats fail once",
+			fault:     &elasticsearchtest.Fault{Path: path + "/_stats", Status: http.StatusServiceUnavailable, Times: 1},
+			wantOut:   "is started.",
+			wantState: "started",
+		},
+		{
+			name:    "start fails",
+			fault:   &elasticsearchtest.Fault{Method: http.MethodPost, Path: path + "/deployment/_start", Status: http.StatusForbidden},
+			wantErr: true,
+			wantOut: "Failed to start the vectorDB model. HTTP status code: 403",
+		},
+		{
+			name:      "untrusted certificate",
+			untrusted: true,
+			wantErr:   true,
+			wantOut:   "The trained model '.elser_model_2' is not fully defined after 2 seconds.",
+		},
+	}
+	for _, tc := range tests {
+		// cluster starts a cluster with the model installed, as the script and Start find it
+		cluster := func(t *testing.T) *elasticsearchtest.Server {
+			server, client := serve(t, elasticsearchtest.Options{ModelDefinitionPolls: 2, DeploymentStartPolls: 2})
+			ctx := context.Background()
+			require.NoError(t, client.Do(ctx, http.MethodPut, path+"?wait_for_completion=true", input, nil))
+			if tc.started {
+				for range 2 {
+					require.NoError(t, client.Do(ctx, http.MethodGet, path+"?include=definition_status", nil, nil))
+				}
+				require.NoError(t, client.Do(ctx, http.MethodPost, path+"/deployment/_start?deployment_id=for_search", nil, nil))
+			}
+			if tc.fault != nil {
+				server.Inject(*tc.fault)
+			}
+			return server
+		}
+		// certificate is the CA certificate that the script and Start trust
+		certificate := func(server *elasticsearchtest.Server) string {
+			if tc.untrusted {
+				return untrustedCertificate()
+			}
+			return server.CertificateBase64()
+		}
+
+		t.Run(tc.name+"/script", func(t *testing.T) {
+			server := cluster(t)
+			out, err := runScript(t, "start_vectordb_model.sh", server, certificate(server))
+			if tc.wantErr {
+				assert.Error(t, err, out)
+			} else {
+				assert.NoError(t, err, out)
+			}
+			assert.Contains(t, out, tc.wantOut)
+			if tc.wantState != "" {
+				assert.Equal(t, tc.wantState, server.DeploymentState(".elser_model_2", DeploymentID))
+			}
+		})
+		t.Run(tc.name+"/Start", func(t *testing.T) {
+			server := cluster(t)
+			c := server.Credentials("admin")
+			c.CertificateBase64 = certificate(server)
+			client, err := elasticsearch.New(c)
+			require.NoError(t, err)
+			err = Start(context.Background(), client, ".elser_model_2", fastStart)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tc.wantState != "" {
+				assert.Equal(t, tc.wantState, server.DeploymentState(".elser_model_2", DeploymentID))
+			}
+		})
+	}
+}
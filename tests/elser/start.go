@@ -0,0 +1,164 @@
+package elser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+)
+
+// DeploymentID is the deployment of the model that the module starts and that searches use.
+const DeploymentID = "for_search"
+
+// StartOptions configure Start.
+type StartOptions struct {
+	// Timeout is how long to wait for the model to be defined and its deployment to start, 15 minutes when zero
+	Timeout time.Duration
+	// PollInterval is the delay between two polls, 10 seconds when zero
+	PollInterval time.Duration
+	// Logf logs the progress, nothing is logged when nil
+	Logf func(format string, args ...any)
+}
+
+func (o StartOptions) withDefaults() StartOptions {
+	if o.Timeout == 0 {
+		o.Timeout = 15 * time.Minute
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = 10 * time.Second
+	}
+	if o.Logf == nil {
+		o.Logf = func(string, ...any) {}
+	}
+	return o
+}
+
+// Deployment is the state of a trained model deployment in _ml/trained_models/<model>/_stats.
+type Deployment struct {
+	DeploymentID string `json:"deployment_id"`
+	// State is starting, started, stopping or failed
+	State string `json:"state"`
+	// Reason explains a failed state
+	Reason           string `json:"reason"`
+	AllocationStatus struct {
+		// State is starting, started or fully_allocated
+		State string `json:"state"`
+	} `json:"allocation_status"`
+}
+
+// Started reports whether the deployment serves requests.
+func (d Deployment) Started() bool {
+	return d.State == "started" && (d.AllocationStatus.State == "started" || d.AllocationStatus.State == "fully_allocated")
+}
+
+// Start waits until the model is fully defined, which takes a few minutes after it is installed, starts its
+// for_search deployment and waits until the deployment is started. A deployment that is already starting or
+// started is not started again. Transient errors are retried until the timeout.
+func Start(ctx context.Context, client *elasticsearch.Client, model string, opts StartOptions) error {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	path := "/_ml/trained_models/" + url.PathEscape(model)
+
+	err := poll(ctx, opts.PollInterval, func() (bool, error) {
+		var models struct {
+			Configs []struct {
+				FullyDefined bool `json:"fully_defined"`
+			} `json:"trained_model_configs"`
+		}
+		if err := client.Do(ctx, http.MethodGet, path+"?include=definition_status", nil, &models); err != nil {
+			return false, err
+		}
+		if len(models.Configs) == 0 {
+			return false, fmt.Errorf("trained model %s is not installed", model)
+		}
+		return models.Configs[0].FullyDefined, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for trained model %s to be fully defined: %w", model, err)
+	}
+	opts.Logf("trained model %s is fully defined", model)
+
+	var deployment *Deployment
+	err = poll(ctx, opts.PollInterval, func() (bool, error) {
+		var err error
+		deployment, err = deploymentStats(ctx, client, path)
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("reading the deployments of trained model %s: %w", model, err)
+	}
+	if deployment == nil {
+		err := client.Do(ctx, http.MethodPost, path+"/deployment/_start?deployment_id="+DeploymentID+"&wait_for=starting", nil, nil)
+		var e *elasticsearch.Error
+		switch {
+		case errors.As(err, &e) && e.StatusCode == http.StatusConflict:
+			opts.Logf("deployment %s of trained model %s already exists", DeploymentID, model)
+		case err != nil:
+			return fmt.Errorf("starting deployment %s of trained model %s: %w", DeploymentID, model, err)
+		default:
+			opts.Logf("starting deployment %s of trained model %s", DeploymentID, model)
+		}
+	}
+
+	err = poll(ctx, opts.PollInterval, func() (bool, error) {
+		deployment, err := deploymentStats(ctx, client, path)
+		switch {
+		case err != nil:
+			return false, err
+		case deployment == nil:
+			return false, nil
+		case deployment.State == "failed":
+			return false, fmt.Errorf("the deployment failed: %s", deployment.Reason)
+		}
+		return deployment.Started(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for deployment %s of trained model %s to start: %w", DeploymentID, model, err)
+	}
+	opts.Logf("deployment %s of trained model %s is started", DeploymentID, model)
+	return nil
+}
+
+// deploymentStats returns the for_search deployment of the model, or nil if there is none
+func deploymentStats(ctx context.Context, client *elasticsearch.Client, path string) (*Deployment, error) {
+	var stats struct {
+		Stats []struct {
+			DeploymentStats *Deployment `json:"deployment_stats"`
+		} `json:"trained_model_stats"`
+	}
+	if err := client.Do(ctx, http.MethodGet, path+"/_stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	for _, s := range stats.Stats {
+		if s.DeploymentStats != nil && s.DeploymentStats.DeploymentID == DeploymentID {
+			return s.DeploymentStats, nil
+		}
+	}
+	return nil, nil
+}
+
+// poll calls done every interval until it reports true, fails with an error that is not retryable or the context
+// ends. The last retryable error is returned with the error of the context.
+func poll(ctx context.Context, interval time.Duration, done func() (bool, error)) error {
+	var last error
+	for {
+		ok, err := done()
+		switch {
+		case err == nil && ok:
+			return nil
+		case err != nil && !elasticsearch.Retryable(err):
+			return err
+		}
+		last = err
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), last)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package elser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+// deploymentServer answers the definition status and stats of a model from scripts, behind the TLS, authentication
+// and faults of the fake cluster: every poll takes the next entry of its script, and the last entry repeats
+type deploymentServer struct {
+	mu sync.Mutex
+	// defined is the fully_defined value of each definition status poll
+	defined []bool
+	// stats is the for_search deployment of each stats poll, nil for none
+	stats []*Deployment
+	// startStatus is the status code of the start request
+	startStatus int
+}
+
+func next[T any](script *[]T) T {
+	value := (*script)[0]
+	if len(*script) > 1 {
+		*script = (*script)[1:]
+	}
+	return value
+}
+
+func (s *deploymentServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_ml/trained_models/{id}", func(w http.ResponseWriter, r *http.Request) {
+		defer s.lock()()
+		_ = json.NewEncoder(w).Encode(map[string]any{"trained_model_configs": []any{map[string]any{"model_id": r.PathValue("id"), "fully_defined": next(&s.defined)}}})
+	})
+	mux.HandleFunc("GET /_ml/trained_models/{id}/_stats", func(w http.ResponseWriter, r *http.Request) {
+		defer s.lock()()
+		stats := []any{map[string]any{"model_id": r.PathValue("id")}}
+		if deployment := next(&s.stats); deployment != nil {
+			stats = append(stats, map[string]any{"model_id": r.PathValue("id"), "deployment_stats": deployment})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"trained_model_stats": stats})
+	})
+	mux.HandleFunc("POST /_ml/trained_models/{id}/deployment/_start", func(w http.ResponseWriter, r *http.Request) {
+		defer s.lock()()
+		w.WriteHeader(s.startStatus)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	return mux
+}
+
+// lock locks the server and returns the unlock
+func (s *deploymentServer) lock() func() {
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// serve serves the scripts of the deployment server and returns the fake cluster with a client for admin
+func (s *deploymentServer) serve(t *testing.T) (*elasticsearchtest.Server, *elasticsearch.Client) {
+	t.Helper()
+	return serve(t, elasticsearchtest.Options{Handler: s.routes()})
+}
+
+func deployment(state string, allocation string) *Deployment {
+	d := &Deployment{DeploymentID: DeploymentID, State: state}
+	d.AllocationStatus.State = allocation
+	return d
+}
+
+var fastStart = StartOptions{Timeout: time.Second, PollInterval: time.Millisecond}
+
+const (
+	definition = "GET /_ml/trained_models/.elser_model_2?include=definition_status"
+	stats      = "GET /_ml/trained_models/.elser_model_2/_stats"
+	start      = "POST /_ml/trained_models/.elser_model_2/deployment/_start?deployment_id=for_search&wait_for=starting"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name         string
+		server       *deploymentServer
+		wantRequests []string
+	}{
+		{
+			name: "waits for the definition and the allocation",
+			server: &deploymentServer{
+				defined:     []bool{false, false, true},
+				stats:       []*Deployment{nil, deployment("starting", "starting"), deployment("started", "starting"), deployment("started", "fully_allocated")},
+				startStatus: http.StatusOK,
+			},
+			wantRequests: []string{definition, definition, definition, stats, start, stats, stats, stats},
+		},
+		{
+			name: "already started",
+			server: &deploymentServer{
+				defined: []bool{true},
+				stats:   []*Deployment{deployment("started", "started")},
+			},
+			wantRequests: []string{definition, stats, stats},
+		},
+		{
+			name: "already starting",
+			server: &deploymentServer{
+				defined: []bool{true},
+				stats:   []*Deployment{deployment("starting", "starting"), deployment("started", "started")},
+			},
+			wantRequests: []string{definition, stats, stats},
+		},
+		{
+			name: "started by someone else in between",
+			server: &deploymentServer{
+				defined:     []bool{true},
+				stats:       []*Deployment{nil, deployment("started", "started")},
+				startStatus: http.StatusConflict,
+			},
+			wantRequests: []string{definition, stats, start, stats},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := tc.server.serve(t)
+			err := Start(context.Background(), client, ".elser_model_2", fastStart)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRequests, server.Requests())
+		})
+	}
+}
+
+func TestStartFails(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  *deploymentServer
+		wantErr string
+		// timesOut is whether the error is the timeout
+		timesOut bool
+	}{
+		{
+			name:     "never defined",
+			server:   &deploymentServer{defined: []bool{false}},
+			wantErr:  "waiting for trained model .elser_model_2 to be fully defined",
+			timesOut: true,
+		},
+		{
+			name:     "never allocated",
+			server:   &deploymentServer{defined: []bool{true}, stats: []*Deployment{nil, deployment("started", "starting")}, startStatus: http.StatusOK},
+			wantErr:  "waiting for deployment for_search of trained model .elser_model_2 to start",
+			timesOut: true,
+		},
+		{
+			name:    "deployment failed",
+			server:  &deploymentServer{defined: []bool{true}, stats: []*Deployment{nil, {DeploymentID: DeploymentID, State: "failed", Reason: "not enough memory"}}, startStatus: http.StatusOK},
+			wantErr: "waiting for deployment for_search of trained model .elser_model_2 to start: the deployment failed: not enough memory",
+		},
+		{
+			name:    "start rejected",
+			server:  &deploymentServer{defined: []bool{true}, stats: []*Deployment{nil}, startStatus: http.StatusBadRequest},
+			wantErr: "starting deployment for_search of trained model .elser_model_2: POST",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := fastStart
+			opts.Timeout = 50 * time.Millisecond
+			_, client := tc.server.serve(t)
+			err := Start(context.Background(), client, ".elser_model_2", opts)
+			assert.ErrorContains(t, err, tc.wantErr)
+			if tc.timesOut {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestStartRetriesTransientErrors(t *testing.T) {
+	defined := &deploymentServer{defined: []bool{true}, stats: []*Deployment{deployment("started", "started")}}
+	server, client := defined.serve(t)
+	server.Inject(elasticsearchtest.Fault{Path: "/_ml/trained_models/.elser_model_2", Status: http.StatusServiceUnavailable, Times: 2})
+	server.Inject(elasticsearchtest.Fault{Path: "/_ml/trained_models/.elser_model_2/_stats", Status: http.StatusBadGateway, Times: 1})
+
+	require.NoError(t, Start(context.Background(), client, ".elser_model_2", fastStart))
+	assert.Equal(t, []string{definition, definition, definition, stats, stats, stats}, server.Requests())
+}
+
+func TestInstallAndStart(t *testing.T) {
+	server, client := serve(t, elasticsearchtest.Options{ModelDefinitionPolls: 2, DeploymentStartPolls: 2})
+	server.AddTrainedModel(".elser_model_1")
+	server.Inject(elasticsearchtest.Fault{Method: http.MethodPut, Path: putModel, Status: http.StatusServiceUnavailable, Times: 2})
+	ctx := context.Background()
+
+	plan, err := Install(ctx, client, ".elser_model_2", Options{Backoff: fastBackoff})
+	require.NoError(t, err)
+	assert.Equal(t, Plan{Delete: []string{".elser_model_1"}, Put: true}, plan)
+	require.NoError(t, Start(ctx, client, ".elser_model_2", fastStart))
+	assert.Equal(t, []string{".elser_model_2", langIdent}, server.TrainedModels())
+	assert.Equal(t, "started", server.DeploymentState(".elser_model_2", DeploymentID))
+
+	requests := len(server.Requests())
+	plan, err = Install(ctx, client, ".elser_model_2", Options{Backoff: fastBackoff})
+	require.NoError(t, err)
+	assert.Equal(t, Plan{}, plan, "installing again changes nothing")
+	require.NoError(t, Start(ctx, client, ".elser_model_2", fastStart))
+	assert.Equal(t, []string{"GET /_ml/trained_models", definition, stats, stats}, server.Requests()[requests:])
+}
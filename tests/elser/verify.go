@@ -0,0 +1,154 @@
+package elser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+var (
+	// sparseVectorFrom is the first version with the sparse_vector query, which replaces text_expansion
+	sparseVectorFrom = versions.MustParse("8.15")
+	// sparseVectorFieldFrom is the first version with the sparse_vector field type, which replaces rank_features
+	sparseVectorFieldFrom = versions.MustParse("8.11")
+)
+
+const (
+	verifyText  = "Elasticsearch is a distributed search and analytics engine"
+	verifyQuery = "search engine"
+)
+
+// VerifyOptions configure Verify.
+type VerifyOptions struct {
+	// Name is the name of the ingest pipeline and the index that Verify creates and deletes, elser-verify-<unix nano>
+	// when empty
+	Name string
+	// Logf logs the progress, nothing is logged when nil
+	Logf func(format string, args ...any)
+}
+
+func (o VerifyOptions) withDefaults() VerifyOptions {
+	if o.Name == "" {
+		o.Name = fmt.Sprintf("elser-verify-%d", time.Now().UnixNano())
+	}
+	if o.Logf == nil {
+		o.Logf = func(string, ...any) {}
+	}
+	return o
+}
+
+// Verify checks that model is installed with a started for_search deployment, and that the deployment expands
+// text: it creates an ingest pipeline with an inference processor, indexes a document through it and finds the
+// document with a sparse_vector query, or a text_expansion query before 8.15. The pipeline and the index are
+// deleted afterwards.
+func Verify(ctx context.Context, client *elasticsearch.Client, model string, opts VerifyOptions) error {
+	opts = opts.withDefaults()
+	path := "/_ml/trained_models/" + url.PathEscape(model)
+
+	var models trainedModels
+	if err := client.Do(ctx, http.MethodGet, path, nil, &models); err != nil {
+		var e *elasticsearch.Error
+		if errors.As(err, &e) && e.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("trained model %s is not installed", model)
+		}
+		return fmt.Errorf("reading trained model %s: %w", model, err)
+	}
+	deployment, err := deploymentStats(ctx, client, path)
+	switch {
+	case err != nil:
+		return fmt.Errorf("reading the deployments of trained model %s: %w", model, err)
+	case deployment == nil:
+		return fmt.Errorf("trained model %s has no deployment %s", model, DeploymentID)
+	case !deployment.Started():
+		return fmt.Errorf("deployment %s of trained model %s is %s with allocation %s, expected started", DeploymentID, model, deployment.State, deployment.AllocationStatus.State)
+	}
+	opts.Logf("deployment %s of trained model %s is started", DeploymentID, model)
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := versions.Parse(info.Version.Number)
+	if err != nil {
+		return fmt.Errorf("the cluster version: %w", err)
+	}
+	tokens, err := expand(ctx, client, opts.Name, version)
+	if err != nil {
+		return err
+	}
+	opts.Logf("deployment %s of trained model %s expanded %q into %d tokens", DeploymentID, model, verifyText, tokens)
+	return nil
+}
+
+// expand indexes verifyText into the content_embedding field through a pipeline named name, searches it with
+// verifyQuery and returns the number of tokens of the document it finds
+func expand(ctx context.Context, client *elasticsearch.Client, name string, version versions.ElasticsearchVersion) (tokens int, err error) {
+	pipeline := map[string]any{
+		"description": "Expands content with the " + DeploymentID + " deployment",
+		"processors": []any{map[string]any{"inference": map[string]any{
+			"model_id":     DeploymentID,
+			"input_output": []any{map[string]any{"input_field": "content", "output_field": "content_embedding"}},
+		}}},
+	}
+	if err := client.Do(ctx, http.MethodPut, "/_ingest/pipeline/"+name, pipeline, nil); err != nil {
+		return 0, fmt.Errorf("creating ingest pipeline %s: %w", name, err)
+	}
+	defer func() {
+		if deleteErr := client.Do(ctx, http.MethodDelete, "/_ingest/pipeline/"+name, nil, nil); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting ingest pipeline %s: %w", name, deleteErr))
+		}
+	}()
+
+	fieldType := "rank_features"
+	if !version.LessThan(sparseVectorFieldFrom) {
+		fieldType = "sparse_vector"
+	}
+	mappings := map[string]any{"mappings": map[string]any{"properties": map[string]any{
+		"content":           map[string]any{"type": "text"},
+		"content_embedding": map[string]any{"type": fieldType},
+	}}}
+	if err := client.Do(ctx, http.MethodPut, "/"+name, mappings, nil); err != nil {
+		return 0, fmt.Errorf("creating index %s: %w", name, err)
+	}
+	defer func() {
+		if deleteErr := client.Do(ctx, http.MethodDelete, "/"+name, nil, nil); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting index %s: %w", name, deleteErr))
+		}
+	}()
+
+	document := map[string]any{"content": verifyText}
+	if err := client.Do(ctx, http.MethodPut, "/"+name+"/_doc/1?pipeline="+name+"&refresh=wait_for", document, nil); err != nil {
+		return 0, fmt.Errorf("indexing into %s through ingest pipeline %s: %w", name, name, err)
+	}
+
+	query := map[string]any{"sparse_vector": map[string]any{"field": "content_embedding", "inference_id": DeploymentID, "query": verifyQuery}}
+	if version.LessThan(sparseVectorFrom) {
+		query = map[string]any{"text_expansion": map[string]any{"content_embedding": map[string]any{"model_id": DeploymentID, "model_text": verifyQuery}}}
+	}
+	var found struct {
+		Hits struct {
+			Hits []struct {
+				Source struct {
+					ContentEmbedding map[string]float64 `json:"content_embedding"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := client.Do(ctx, http.MethodPost, "/"+name+"/_search", map[string]any{"query": query}, &found); err != nil {
+		return 0, fmt.Errorf("searching %s: %w", name, err)
+	}
+	if len(found.Hits.Hits) == 0 {
+		return 0, fmt.Errorf("searching %s for %q found no document", name, verifyQuery)
+	}
+	tokens = len(found.Hits.Hits[0].Source.ContentEmbedding)
+	if tokens == 0 {
+		return 0, fmt.Errorf("the document in %s has no tokens in content_embedding", name)
+	}
+	return tokens, nil
+}
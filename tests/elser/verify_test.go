@@ -0,0 +1,113 @@
+package elser
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+// fakeCluster starts a fake cluster at the version and returns it with a client for admin
+func fakeCluster(t *testing.T, version string) (*elasticsearchtest.Server, *elasticsearch.Client) {
+	t.Helper()
+	return serve(t, elasticsearchtest.Options{Version: version, DeploymentStartPolls: 100})
+}
+
+func TestVerify(t *testing.T) {
+	for _, version := range []string{"8.19.3", "8.12.2", "8.10.4"} {
+		t.Run(version, func(t *testing.T) {
+			server, client := fakeCluster(t, version)
+			_, err := Install(context.Background(), client, ".elser_model_2", Options{Backoff: fastBackoff})
+			require.NoError(t, err)
+			// wait_for=started starts the deployment at once
+			require.NoError(t, client.Do(context.Background(), http.MethodPost, "/_ml/trained_models/.elser_model_2/deployment/_start?deployment_id=for_search", nil, nil))
+
+			var logs []string
+			err = Verify(context.Background(), client, ".elser_model_2", VerifyOptions{Name: "verify", Logf: func(format string, args ...any) {
+				logs = append(logs, format)
+			}})
+			require.NoError(t, err)
+			assert.Len(t, logs, 2)
+			_, ok := server.Documents("verify")
+			assert.False(t, ok, "the index is deleted")
+			assert.Contains(t, server.Requests(), "DELETE /_ingest/pipeline/verify")
+		})
+	}
+}
+
+func TestVerifyFails(t *testing.T) {
+	tests := []struct {
+		name string
+		// deploy is how far the model is deployed: "" not at all, "installed", "starting" or "started"
+		deploy  string
+		fault   *elasticsearchtest.Fault
+		wantErr string
+		// cleanedUp is whether the pipeline and the index were created and must be deleted
+		cleanedUp bool
+	}{
+		{name: "not installed", wantErr: "trained model .elser_model_2 is not installed"},
+		{name: "not deployed", deploy: "installed", wantErr: "trained model .elser_model_2 has no deployment for_search"},
+		{name: "starting", deploy: "starting", wantErr: "deployment for_search of trained model .elser_model_2 is starting with allocation starting, expected started"},
+		{
+			name:    "stats fail",
+			deploy:  "started",
+			fault:   &elasticsearchtest.Fault{Path: "/_ml/trained_models/.elser_model_2/_stats", Status: http.StatusForbidden},
+			wantErr: "reading the deployments of trained model .elser_model_2: GET /_ml/trained_models/.elser_model_2/_stats: 403",
+		},
+		{
+			name:      "indexing fails",
+			deploy:    "started",
+			fault:     &elasticsearchtest.Fault{Path: "/verify/_doc/1", Status: http.StatusTooManyRequests},
+			wantErr:   "indexing into verify through ingest pipeline verify: PUT /verify/_doc/1?pipeline=verify&refresh=wait_for: 429",
+			cleanedUp: true,
+		},
+		{
+			name:      "search fails",
+			deploy:    "started",
+			fault:     &elasticsearchtest.Fault{Path: "/verify/_search", Status: http.StatusBadRequest},
+			wantErr:   "searching verify: POST /verify/_search: 400",
+			cleanedUp: true,
+		},
+		{
+			name:      "index not deleted",
+			deploy:    "started",
+			fault:     &elasticsearchtest.Fault{Method: http.MethodDelete, Path: "/verify", Status: http.StatusInternalServerError},
+			wantErr:   "deleting index verify: DELETE /verify: 500",
+			cleanedUp: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := fakeCluster(t, "8.19.3")
+			ctx := context.Background()
+			path := "/_ml/trained_models/.elser_model_2"
+			if tc.deploy != "" {
+				server.AddTrainedModel(".elser_model_2")
+			}
+			switch tc.deploy {
+			case "starting":
+				require.NoError(t, client.Do(ctx, http.MethodPost, path+"/deployment/_start?deployment_id=for_search&wait_for=starting", nil, nil))
+			case "started":
+				require.NoError(t, client.Do(ctx, http.MethodPost, path+"/deployment/_start?deployment_id=for_search", nil, nil))
+			}
+			if tc.fault != nil {
+				server.Inject(*tc.fault)
+			}
+
+			err := Verify(ctx, client, ".elser_model_2", VerifyOptions{Name: "verify"})
+			assert.ErrorContains(t, err, tc.wantErr)
+			if tc.cleanedUp {
+				assert.Contains(t, server.Requests(), "DELETE /_ingest/pipeline/verify")
+				assert.Contains(t, server.Requests(), "DELETE /verify")
+			} else {
+				assert.NotContains(t, server.Requests(), "PUT /_ingest/pipeline/verify")
+			}
+		})
+	}
+}
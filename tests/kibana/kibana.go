@@ -0,0 +1,209 @@
+// Package kibana verifies the Kibana dashboard that the fully-configurable solution deploys on Code Engine: that
+// it becomes available, runs the version of the Elasticsearch instance and lets its user log in.
+package kibana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+// Options configure Verify.
+type Options struct {
+	// Username and Password are the login of the Kibana user, kibana_user in the solution
+	Username string
+	Password string
+	// Version is the version Kibana must run, the version_number of es_metadata in the solution
+	Version string
+	// Timeout is how long to wait for Kibana to be available, 15 minutes when zero
+	Timeout time.Duration
+	// PollInterval is the delay between two polls of the status, 15 seconds when zero
+	PollInterval time.Duration
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+	// Logf logs the progress, nothing is logged when nil
+	Logf func(format string, args ...any)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = 15 * time.Minute
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = 15 * time.Second
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.Logf == nil {
+		o.Logf = func(string, ...any) {}
+	}
+	return o
+}
+
+// Status is the part of /api/status that Verify reads.
+type Status struct {
+	Name    string `json:"name"`
+	Version struct {
+		Number string `json:"number"`
+	} `json:"version"`
+	Status struct {
+		Overall struct {
+			// Level is available, degraded, unavailable or critical
+			Level   string `json:"level"`
+			Summary string `json:"summary"`
+		} `json:"overall"`
+	} `json:"status"`
+}
+
+// Error is a response of Kibana with an unexpected status code.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Verify polls /api/status at endpoint as the user until Kibana reports that it is available, checks that it runs
+// the version, and logs in as the user with the basic provider. Kibana answers 503 while it starts and Code Engine
+// answers 502 or 503 while the app scales up, so these are polled through; a rejected login is not.
+func Verify(ctx context.Context, endpoint string, opts Options) error {
+	opts = opts.withDefaults()
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	want, err := versions.Parse(opts.Version)
+	if err != nil {
+		return fmt.Errorf("the expected version: %w", err)
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	var status Status
+	// last is the reason of the last poll that completed
+	var last error
+	for {
+		err := get(pollCtx, opts, endpoint+"/api/status", nil, &status)
+		var e *Error
+		switch {
+		case err == nil && status.Status.Overall.Level == "available":
+		case err == nil:
+			err = fmt.Errorf("kibana is %s: %s", status.Status.Overall.Level, status.Status.Overall.Summary)
+		case errors.As(err, &e) && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden):
+			return fmt.Errorf("reading the status of %s as %s: %w", endpoint, opts.Username, err)
+		}
+		if err == nil {
+			break
+		}
+		if pollCtx.Err() == nil || last == nil {
+			last = err
+			opts.Logf("waiting for kibana at %s: %v", endpoint, last)
+		}
+		select {
+		case <-pollCtx.Done():
+			return fmt.Errorf("waiting for kibana at %s to be available: %w", endpoint, errors.Join(pollCtx.Err(), last))
+		case <-time.After(opts.PollInterval):
+		}
+	}
+	opts.Logf("kibana %s at %s is available", status.Version.Number, endpoint)
+
+	got, err := versions.Parse(status.Version.Number)
+	if err != nil {
+		return fmt.Errorf("the kibana version: %w", err)
+	}
+	if !want.Matches(got) {
+		return fmt.Errorf("kibana runs version %s, expected %s", got, want)
+	}
+
+	if err := login(ctx, opts, endpoint); err != nil {
+		return fmt.Errorf("logging in to %s as %s: %w", endpoint, opts.Username, err)
+	}
+	opts.Logf("logged in to kibana at %s as %s", endpoint, opts.Username)
+	return nil
+}
+
+// login logs in with the basic provider and checks that the session is the user's
+func login(ctx context.Context, opts Options, endpoint string) error {
+	body, err := json.Marshal(map[string]any{
+		"providerType": "basic",
+		"providerName": "basic",
+		"currentURL":   endpoint + "/login",
+		"params":       map[string]string{"username": opts.Username, "password": opts.Password},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/internal/security/login", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("kbn-xsrf", "true")
+	resp, err := send(opts, req, nil)
+	if err != nil {
+		return err
+	}
+	cookies := resp.Cookies()
+	if len(cookies) == 0 {
+		return errors.New("the login response has no session cookie")
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := get(ctx, opts, endpoint+"/internal/security/me", cookies, &me); err != nil {
+		return err
+	}
+	if me.Username != opts.Username {
+		return fmt.Errorf("the session is the one of %q", me.Username)
+	}
+	return nil
+}
+
+// get sends a GET request, with the cookies when there are some and with basic authentication otherwise
+func get(ctx context.Context, opts Options, rawURL string, cookies []*http.Cookie, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if len(cookies) == 0 {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	_, err = send(opts, req, out)
+	return err
+}
+
+// send sends the request and decodes a 2xx response into out when it is not nil
+func send(opts Options, req *http.Request, out any) (*http.Response, error) {
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &Error{Method: req.Method, Path: req.URL.Path, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return nil, fmt.Errorf("%s %s: decoding the response: %w", req.Method, req.URL.Path, err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,213 @@
+package kibana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// standIn answers the status and login APIs of Kibana for kibana_user
+type standIn struct {
+	mu      sync.Mutex
+	version string
+	// starting is the number of status polls that are answered as Kibana starts, with 503 and a plain text body
+	starting int
+	// degraded is the number of status polls that report Kibana as degraded after it started
+	degraded int
+	// noBasicProvider rejects logins, as when the basic provider is disabled
+	noBasicProvider bool
+	sessions        map[string]string
+	polls           int
+}
+
+const (
+	username = "kibana_user"
+	password = "kibana-Passw0rd" // pragma: allowlist secret
+)
+
+func (s *standIn) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.polls++
+		if s.starting > 0 {
+			s.starting--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("Kibana server is not ready yet"))
+			return
+		}
+		if u, p, ok := r.BasicAuth(); !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`))
+			return
+		}
+		level, summary := "available", "All services and plugins are available"
+		if s.degraded > 0 {
+			s.degraded--
+			level, summary = "degraded", "1 service is degraded: elasticsearch"
+		}
+		var status Status
+		status.Name = "kibana"
+		status.Version.Number = s.version
+		status.Status.Overall.Level = level
+		status.Status.Overall.Summary = summary
+		_ = json.NewEncoder(w).Encode(status)
+	})
+	mux.HandleFunc("POST /internal/security/login", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.Header.Get("kbn-xsrf") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Request must contain a kbn-xsrf header."}`))
+			return
+		}
+		var body struct {
+			ProviderType string            `json:"providerType"`
+			Params       map[string]string `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ProviderType != "basic" || s.noBasicProvider ||
+			body.Params["username"] != username || body.Params["password"] != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`))
+			return
+		}
+		if s.sessions == nil {
+			s.sessions = map[string]string{}
+		}
+		sid := "sid-" + body.Params["username"]
+		s.sessions[sid] = body.Params["username"]
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid, Path: "/", HttpOnly: true, Secure: true})
+		_, _ = w.Write([]byte(`{"location":"/"}`))
+	})
+	mux.HandleFunc("GET /internal/security/me", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		cookie, err := r.Cookie("sid")
+		if err != nil || s.sessions[cookie.Value] == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"username": s.sessions[cookie.Value], "authentication_provider": map[string]string{"type": "basic", "name": "basic"}})
+	})
+	return mux
+}
+
+// serve starts the stand-in and returns its endpoint and a client that trusts it
+func serve(t *testing.T, s *standIn) (string, *http.Client) {
+	t.Helper()
+	server := httptest.NewTLSServer(s.routes())
+	t.Cleanup(server.Close)
+	return server.URL, server.Client()
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		kibana    *standIn
+		version   string
+		wantPolls int
+	}{
+		{name: "available", kibana: &standIn{version: "8.19.3"}, version: "8.19.3", wantPolls: 1},
+		{name: "starting then degraded", kibana: &standIn{version: "8.19.3", starting: 2, degraded: 1}, version: "8.19.3", wantPolls: 4},
+		{name: "version without patch", kibana: &standIn{version: "9.1.4"}, version: "9.1", wantPolls: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint, client := serve(t, tc.kibana)
+			var logs []string
+			err := Verify(context.Background(), endpoint+"/", Options{
+				Username:     username,
+				Password:     password,
+				Version:      tc.version,
+				PollInterval: time.Millisecond,
+				Client:       client,
+				Logf:         func(format string, args ...any) { logs = append(logs, format) },
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPolls, tc.kibana.polls)
+			assert.Len(t, logs, tc.wantPolls+1, "a line per failed poll, availability and login")
+		})
+	}
+}
+
+func TestVerifyFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		kibana   *standIn
+		password string
+		version  string
+		wantErr  string
+		// timesOut is whether the error is the timeout
+		timesOut bool
+	}{
+		{
+			name:    "other version",
+			kibana:  &standIn{version: "8.15.2"},
+			wantErr: "kibana runs version 8.15.2, expected 8.19.3",
+		},
+		{
+			name:     "wrong password",
+			kibana:   &standIn{version: "8.19.3"},
+			password: "wrong", // pragma: allowlist secret
+			wantErr:  "reading the status of https://127.0.0.1:",
+		},
+		{
+			name:    "login rejected",
+			kibana:  &standIn{version: "8.19.3", noBasicProvider: true},
+			wantErr: "logging in to https://127.0.0.1:",
+		},
+		{
+			name:     "never starts",
+			kibana:   &standIn{version: "8.19.3", starting: 1000},
+			wantErr:  "GET /api/status: 503 Service Unavailable: Kibana server is not ready yet",
+			timesOut: true,
+		},
+		{
+			name:     "stays degraded",
+			kibana:   &standIn{version: "8.19.3", degraded: 1000},
+			wantErr:  "kibana is degraded: 1 service is degraded: elasticsearch",
+			timesOut: true,
+		},
+		{
+			name:    "not a version",
+			kibana:  &standIn{version: "8.19.3"},
+			version: "latest",
+			wantErr: "the expected version",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint, client := serve(t, tc.kibana)
+			if tc.password == "" {
+				tc.password = password
+			}
+			if tc.version == "" {
+				tc.version = "8.19.3"
+			}
+			err := Verify(context.Background(), endpoint, Options{
+				Username:     username,
+				Password:     tc.password,
+				Version:      tc.version,
+				Timeout:      50 * time.Millisecond,
+				PollInterval: time.Millisecond,
+				Client:       client,
+			})
+			assert.ErrorContains(t, err, tc.wantErr)
+			if tc.timesOut {
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+			} else {
+				assert.NotErrorIs(t, err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
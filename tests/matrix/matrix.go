@@ -0,0 +1,148 @@
+// Package matrix runs a test for every Elasticsearch version and plan combination and
+// summarises the outcome as a compatibility table.
+package matrix
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+// Status is the outcome of a single version and plan combination.
+type Status string
+
+const (
+	Passed  Status = "pass"
+	Failed  Status = "FAIL"
+	Skipped Status = "skip"
+)
+
+// Case is a single version and plan combination.
+type Case struct {
+	Plan    string
+	Version string
+}
+
+// PlanVersions is the list of versions the catalog offers for a plan.
+type PlanVersions struct {
+	Plan     string
+	Versions []string
+}
+
+// Table records the status of every combination that was run.
+type Table struct {
+	plans    []string
+	versions []versions.ElasticsearchVersion
+	results  map[Case]Status
+}
+
+// NewTable returns an empty table.
+func NewTable() *Table {
+	return &Table{results: map[Case]Status{}}
+}
+
+// Record stores the status of a combination, adding its plan and version to the table if needed.
+func (tb *Table) Record(c Case, status Status) error {
+	version, err := versions.Parse(c.Version)
+	if err != nil {
+		return err
+	}
+	if !contains(tb.plans, c.Plan) {
+		tb.plans = append(tb.plans, c.Plan)
+	}
+	known := false
+	for _, existing := range tb.versions {
+		if existing.String() == version.String() {
+			known = true
+			break
+		}
+	}
+	if !known {
+		tb.versions = append(tb.versions, version)
+	}
+	tb.results[c] = status
+	return nil
+}
+
+// Result returns the recorded status of a combination.
+func (tb *Table) Result(c Case) (Status, bool) {
+	status, ok := tb.results[c]
+	return status, ok
+}
+
+// String renders the table as markdown with one row per version, newest first, and one column per plan.
+// Combinations that the catalog does not offer are shown as "-".
+func (tb *Table) String() string {
+	rows := versions.Sorted(tb.versions)
+
+	var sb strings.Builder
+	sb.WriteString("| version |")
+	for _, plan := range tb.plans {
+		fmt.Fprintf(&sb, " %s |", plan)
+	}
+	sb.WriteString("\n|---|")
+	for range tb.plans {
+		sb.WriteString("---|")
+	}
+	sb.WriteString("\n")
+	for i := len(rows) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "| %s |", rows[i])
+		for _, plan := range tb.plans {
+			status, ok := tb.results[Case{Plan: plan, Version: rows[i].String()}]
+			if !ok {
+				status = "-"
+			}
+			fmt.Fprintf(&sb, " %s |", status)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Run runs fn as a subtest named "<plan>/<version>" for every version offered for every plan,
+// newest version first, and logs the resulting compatibility table.
+func Run(t *testing.T, plans []PlanVersions, fn func(t *testing.T, c Case)) *Table {
+	t.Helper()
+	table := NewTable()
+
+	for _, plan := range plans {
+		parsed, err := versions.ParseAll(plan.Versions)
+		if err != nil {
+			t.Fatalf("cannot build version matrix for plan %s: %v", plan.Plan, err)
+		}
+		if len(parsed) == 0 {
+			t.Errorf("no versions available for plan %s", plan.Plan)
+			continue
+		}
+		sorted := versions.Sorted(parsed)
+		for i := len(sorted) - 1; i >= 0; i-- {
+			c := Case{Plan: plan.Plan, Version: sorted[i].String()}
+			t.Run(c.Plan+"/"+c.Version, func(t *testing.T) {
+				defer func() {
+					status := Passed
+					if t.Failed() {
+						status = Failed
+					} else if t.Skipped() {
+						status = Skipped
+					}
+					_ = table.Record(c, status)
+				}()
+				fn(t, c)
+			})
+		}
+	}
+
+	t.Logf("Elasticsearch version compatibility:\n%s", table)
+	return table
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
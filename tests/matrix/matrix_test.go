@@ -0,0 +1,51 @@
+package matrix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunVisitsEveryCombination(t *testing.T) {
+	var visited []Case
+	table := Run(t, []PlanVersions{
+		{Plan: "enterprise", Versions: []string{"8.10", "8.19", "9.1"}},
+		{Plan: "enterprise-gen2", Versions: []string{"8.19"}},
+	}, func(t *testing.T, c Case) {
+		visited = append(visited, c)
+		if c.Version == "9.1" && c.Plan == "enterprise" {
+			t.Skip("not supported")
+		}
+	})
+
+	assert.Equal(t, []Case{
+		{Plan: "enterprise", Version: "9.1"},
+		{Plan: "enterprise", Version: "8.19"},
+		{Plan: "enterprise", Version: "8.10"},
+		{Plan: "enterprise-gen2", Version: "8.19"},
+	}, visited)
+
+	status, ok := table.Result(Case{Plan: "enterprise", Version: "9.1"})
+	require.True(t, ok)
+	assert.Equal(t, Skipped, status)
+	status, ok = table.Result(Case{Plan: "enterprise", Version: "8.19"})
+	require.True(t, ok)
+	assert.Equal(t, Passed, status)
+	_, ok = table.Result(Case{Plan: "enterprise-gen2", Version: "9.1"})
+	assert.False(t, ok)
+}
+
+func TestTableString(t *testing.T) {
+	table := NewTable()
+	require.NoError(t, table.Record(Case{Plan: "enterprise", Version: "8.10"}, Passed))
+	require.NoError(t, table.Record(Case{Plan: "enterprise", Version: "8.19"}, Failed))
+	require.NoError(t, table.Record(Case{Plan: "platinum", Version: "8.19"}, Passed))
+	require.Error(t, table.Record(Case{Plan: "platinum", Version: "latest"}, Passed))
+
+	assert.Equal(t, ""+
+		"| version | enterprise | platinum |\n"+
+		"|---|---|---|\n"+
+		"| 8.19 | FAIL | pass |\n"+
+		"| 8.10 | pass | - |\n", table.String())
+}
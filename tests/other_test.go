@@ -5,17 +5,27 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testhelper"
+	"github.com/terraform-ibm-modules/ibmcloud-terratest-wrapper/testschematic"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/upgrade"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/users"
 )
 
 func TestRunCompleteExampleOtherVersion(t *testing.T) {
+	skipIfOffline(t)
 	t.Parallel()
 
 	// Generate a 15 char long random string for the admin_pass
@@ -25,6 +35,7 @@ func TestRunCompleteExampleOtherVersion(t *testing.T) {
 		log.Fatal(err)
 	}
 	randomPass := "A1" + base64.URLEncoding.EncodeToString(randomBytes)[:13]
+	testUser := users.User{Name: "testuser", Password: randomPass, Type: "database"} // pragma: allowlist secret
 
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
 		Testing:            t,
@@ -37,9 +48,9 @@ func TestRunCompleteExampleOtherVersion(t *testing.T) {
 			"existing_sm_instance_region": permanentResources["secretsManagerRegion"],
 			"users": []map[string]interface{}{
 				{
-					"name":     "testuser",
-					"password": randomPass, // pragma: allowlist secret
-					"type":     "database",
+					"name":     testUser.Name,
+					"password": testUser.Password,
+					"type":     testUser.Type,
 				},
 			},
 			"admin_pass": randomPass,
@@ -48,7 +59,7 @@ func TestRunCompleteExampleOtherVersion(t *testing.T) {
 	})
 
 	region := options.Region
-	latestVersion, _ := GetRegionVersions(region)
+	latestVersion, _ := GetRegionVersions(region, "enterprise") // complete example default plan
 	options.TerraformVars["elasticsearch_version"] = latestVersion
 
 	options.SkipTestTearDown = true
@@ -61,10 +72,14 @@ func TestRunCompleteExampleOtherVersion(t *testing.T) {
 	expectedOutputs := []string{"port", "hostname"}
 	_, outputErr := testhelper.ValidateTerraformOutputs(outputs, expectedOutputs...)
 	assert.NoErrorf(t, outputErr, "Some outputs not found or nil")
+	assert.NoError(t, checkOutputContract(t, options.TerraformOptions, "examples/complete", false))
+	assert.NoError(t, checkSmoke(t, options.TerraformOptions, "es_admin", latestVersion))
+	assert.NoError(t, checkUsers(t, options.TerraformOptions, "examples/complete", []users.User{testUser}))
 	options.TestTearDown()
 }
 
 func TestRunRestoredDBExample(t *testing.T) {
+	skipIfOffline(t)
 	t.Parallel()
 
 	options := testhelper.TestOptionsDefaultWithVars(&testhelper.TestOptions{
@@ -78,8 +93,79 @@ func TestRunRestoredDBExample(t *testing.T) {
 		},
 		CloudInfoService: sharedInfoSvc,
 	})
+	options.PostApplyHook = func(options *testhelper.TestOptions) error {
+		return checkOutputContract(t, options.TerraformOptions, "examples/backup-restore", false)
+	}
 
 	output, err := options.RunTestConsistency()
 	assert.Nil(t, err, "This should not have errored")
 	assert.NotNil(t, output, "Expected some output")
 }
+
+// Deploy the oldest supported version with the fully-configurable DA and upgrade the Elasticsearch engine in place through every newer version
+func TestRunFullyConfigurableVersionUpgradeChain(t *testing.T) {
+	skipIfOffline(t)
+	t.Parallel()
+
+	region := "us-south"
+	plan := "platinum" // allows every classic version, including 9.x
+	latestVersion, oldestVersion := GetRegionVersions(region, plan)
+	hops, err := upgrade.Chain(GetSupportedVersions(GetRegionVersionList(region), plan, region), oldestVersion, latestVersion)
+	require.NoError(t, err)
+	if len(hops) == 0 {
+		t.Skipf("Only version %s is offered in %s, nothing to upgrade", oldestVersion, region)
+	}
+	logger.Log(t, "Upgrade chain: ", hops)
+
+	options := testhelper.TestOptionsDefault(&testhelper.TestOptions{
+		Testing:      t,
+		TerraformDir: fullyConfigurableSolutionTerraformDir,
+		Prefix:       "es-upg-chain",
+		Region:       region, // skip VPC region picker
+	})
+	options.TestSetup()
+	options.TerraformOptions.Vars = map[string]interface{}{
+		"prefix":                       options.Prefix,
+		"region":                       region,
+		"plan":                         plan,
+		"elasticsearch_version":        oldestVersion,
+		"version_upgrade_skip_backup":  false,
+		"existing_resource_group_name": resourceGroup,
+		"provider_visibility":          "public",
+		"deletion_protection":          false,
+	}
+
+	_, err = terraform.InitAndApplyContextE(t, context.Background(), options.TerraformOptions)
+	if assert.Nil(t, err, "Init and Apply of the oldest version failed") {
+		err = upgrade.Apply(t, context.Background(), options.TerraformOptions, fullyConfigurableDatabaseAddress, hops)
+		assert.Nil(t, err, "This should not have errored")
+	}
+
+	envVal, _ := os.LookupEnv("DO_NOT_DESTROY_ON_FAILURE")
+	if t.Failed() && strings.ToLower(envVal) == "true" {
+		fmt.Println("Terratest failed. Debug the test and delete resources manually.")
+	} else {
+		logger.Log(t, "START: Destroy (upgrade chain)")
+		terraform.DestroyContext(t, context.Background(), options.TerraformOptions)
+		logger.Log(t, "END: Destroy (upgrade chain)")
+	}
+}
+
+// TestRunFullyConfigurableSolutionPublicSchematics deploys with the public endpoint too, which lets the test runner
+// verify the ELSER model and Kibana, as the hostname output is the public one. It is not run in the PR pipeline, where
+// TestRunFullyConfigurableSolutionSchematics covers the apply of the solution.
+func TestRunFullyConfigurableSolutionPublicSchematics(t *testing.T) {
+	skipIfOffline(t)
+	t.Parallel()
+
+	test := setupFullyConfigurableSchematic(t, fmt.Sprintf("%s-fc-pub", icdShortType), "public-and-private")
+	test.options.PostApplyHook = func(options *testschematic.TestSchematicOptions) error {
+		return errors.Join(
+			checkSchematicOutputContract(options, fullyConfigurableSolutionTerraformDir, false),
+			// the default elser_model_type
+			checkElserSchematic(t, options, test.adminPass, ".elser_model_2_linux-x86_64"),
+			checkKibanaSchematic(t, options, test.adminPass, test.adminSecretGroup),
+		)
+	}
+	test.run(t)
+}
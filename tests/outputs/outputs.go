@@ -0,0 +1,334 @@
+// Package outputs declares the outputs that each Terraform module in this repository provides, and checks the
+// declarations in outputs.tf and the outputs of an applied configuration against them.
+package outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// Shape is the kind of value of an output.
+type Shape string
+
+const (
+	String Shape = "string"
+	Number Shape = "number"
+	Bool   Shape = "bool"
+	// List is a list, set or tuple
+	List Shape = "list"
+	// Object is an object or map
+	Object Shape = "object"
+)
+
+// Output is what a module promises about one output.
+type Output struct {
+	Name      string
+	Sensitive bool
+	Shape     Shape
+	// NullOnGen2 outputs are always null for a gen2 instance
+	NullOnGen2 bool
+}
+
+// Contract is the outputs of one module.
+type Contract struct {
+	// Dir is the module directory relative to the repository root
+	Dir     string
+	Outputs []Output
+}
+
+// outputs of the root module that the examples and solutions pass through
+var (
+	id                       = Output{Name: "id", Shape: String}
+	version                  = Output{Name: "version", Shape: String}
+	guid                     = Output{Name: "guid", Shape: String}
+	crn                      = Output{Name: "crn", Shape: String}
+	serviceCredentialsJSON   = Output{Name: "service_credentials_json", Sensitive: true, Shape: Object}
+	serviceCredentialsObject = Output{Name: "service_credentials_object", Sensitive: true, Shape: Object}
+	cbrRuleIDs               = Output{Name: "cbr_rule_ids", Shape: List}
+	adminUser                = Output{Name: "adminuser", Shape: String}
+	usersCredentials         = Output{Name: "users_credentials", Sensitive: true, Shape: List}
+	// the connection outputs come from the ibm_database_connection data source, which is only read for classic instances
+	hostname          = Output{Name: "hostname", Shape: String, NullOnGen2: true}
+	port              = Output{Name: "port", Shape: Number, NullOnGen2: true}
+	certificateBase64 = Output{Name: "certificate_base64", Sensitive: true, Shape: String, NullOnGen2: true}
+)
+
+var nextSteps = []Output{
+	{Name: "next_steps_text", Shape: String},
+	{Name: "next_step_primary_label", Shape: String},
+	{Name: "next_step_primary_url", Shape: String},
+	{Name: "next_step_secondary_label", Shape: String},
+	{Name: "next_step_secondary_url", Shape: String},
+}
+
+// Contracts lists the contract of every module with outputs.
+var Contracts = []Contract{
+	{
+		Dir: ".",
+		Outputs: []Output{
+			id, version, guid, crn, serviceCredentialsJSON, serviceCredentialsObject, cbrRuleIDs, adminUser,
+			usersCredentials, hostname, port, certificateBase64,
+		},
+	},
+	{
+		Dir: "modules/fscloud",
+		Outputs: []Output{
+			id, guid, version, crn, cbrRuleIDs, serviceCredentialsJSON, serviceCredentialsObject, adminUser,
+			hostname, port, certificateBase64, usersCredentials,
+		},
+	},
+	{
+		Dir: "solutions/fully-configurable",
+		Outputs: append([]Output{
+			id, version, guid, crn, serviceCredentialsJSON, serviceCredentialsObject, hostname, port,
+			{Name: "secrets_manager_secrets", Shape: Object},
+			{Name: "admin_pass", Sensitive: true, Shape: String},
+			{Name: "kibana_app_endpoint", Shape: String},
+			{Name: "user_credentials", Sensitive: true, Shape: Object},
+			cbrRuleIDs, adminUser, certificateBase64,
+		}, nextSteps...),
+	},
+	{
+		// hostname and port come from the root module for a new instance, so they are null unless an existing
+		// instance is used
+		Dir: "solutions/fully-configurable-gen2",
+		Outputs: append([]Output{
+			id, version, guid, crn, serviceCredentialsJSON, serviceCredentialsObject, hostname, port,
+			{Name: "secrets_manager_secrets", Shape: Object},
+		}, nextSteps...),
+	},
+	{
+		Dir: "examples/basic",
+		Outputs: []Output{
+			id,
+			{Name: "elasticsearch_crn", Shape: String},
+			version, adminUser, hostname, port, certificateBase64, serviceCredentialsJSON, serviceCredentialsObject,
+		},
+	},
+	{
+		Dir:     "examples/complete",
+		Outputs: []Output{id, version, guid, crn, serviceCredentialsJSON, serviceCredentialsObject, hostname, port},
+	},
+	{
+		Dir:     "examples/fscloud",
+		Outputs: []Output{id, guid, version, hostname, port},
+	},
+	{
+		Dir: "examples/backup-restore",
+		Outputs: []Output{
+			{Name: "restored_icd_elasticsearch_id", Shape: String},
+			{Name: "restored_icd_elasticsearch_version", Shape: String},
+		},
+	},
+}
+
+// For returns the contract of the module in dir, relative to the repository root.
+func For(dir string) (Contract, error) {
+	for _, contract := range Contracts {
+		if contract.Dir == dir {
+			return contract, nil
+		}
+	}
+	return Contract{}, fmt.Errorf("no output contract for %s", dir)
+}
+
+// Output returns the output with the name.
+func (c Contract) Output(name string) (Output, bool) {
+	i := slices.IndexFunc(c.Outputs, func(o Output) bool { return o.Name == name })
+	if i < 0 {
+		return Output{}, false
+	}
+	return c.Outputs[i], true
+}
+
+// Declared returns whether each output declared by the module is sensitive.
+func Declared(m *tfconfig.Module) (map[string]bool, error) {
+	declared := map[string]bool{}
+	for _, body := range m.Files {
+		for _, block := range body.Blocks {
+			if block.Type != "output" || len(block.Labels) != 1 {
+				continue
+			}
+			sensitive, err := boolAttribute(block, "sensitive")
+			if err != nil {
+				return nil, fmt.Errorf("output %q: %w", block.Labels[0], err)
+			}
+			declared[block.Labels[0]] = sensitive
+		}
+	}
+	return declared, nil
+}
+
+func boolAttribute(block *hclsyntax.Block, name string) (bool, error) {
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return false, nil
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return false, diags
+	}
+	if value.IsNull() || !value.Type().Equals(cty.Bool) {
+		return false, fmt.Errorf("%s is not a literal bool", name)
+	}
+	return value.True(), nil
+}
+
+// CheckDeclared compares the outputs declared by the module with the contract.
+func (c Contract) CheckDeclared(m *tfconfig.Module) error {
+	declared, err := Declared(m)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, output := range c.Outputs {
+		sensitive, ok := declared[output.Name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: output %s is not declared", c.Dir, output.Name))
+		case sensitive != output.Sensitive:
+			errs = append(errs, fmt.Errorf("%s: output %s is declared with sensitive = %t", c.Dir, output.Name, sensitive))
+		}
+	}
+	for _, name := range sortedKeys(declared) {
+		if _, ok := c.Output(name); !ok {
+			errs = append(errs, fmt.Errorf("%s: output %s is not in the contract", c.Dir, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Value is an output as printed by `terraform output -json`.
+type Value struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// ParseJSON parses the output of `terraform output -json`.
+func ParseJSON(src string) (map[string]Value, error) {
+	var values map[string]Value
+	if err := json.Unmarshal([]byte(src), &values); err != nil {
+		return nil, fmt.Errorf("parsing terraform output -json: %w", err)
+	}
+	return values, nil
+}
+
+// Check compares the outputs of an applied configuration with the contract. Terraform omits outputs that are null,
+// so a missing output is only reported if it has a value in the contract on this platform.
+func (c Contract) Check(values map[string]Value, gen2 bool) error {
+	var errs []error
+	for _, output := range c.Outputs {
+		value, ok := values[output.Name]
+		isNull := !ok || string(value.Value) == "null"
+		if gen2 && output.NullOnGen2 {
+			if !isNull {
+				errs = append(errs, fmt.Errorf("%s: output %s is not null on gen2", c.Dir, output.Name))
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if value.Sensitive != output.Sensitive {
+			errs = append(errs, fmt.Errorf("%s: output %s has sensitive = %t", c.Dir, output.Name, value.Sensitive))
+		}
+		if !isNull {
+			if shape, err := typeShape(value.Type); err != nil || shape != output.Shape {
+				errs = append(errs, fmt.Errorf("%s: output %s has type %s, want a %s", c.Dir, output.Name, value.Type, output.Shape))
+			}
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		if _, ok := c.Output(name); !ok {
+			errs = append(errs, fmt.Errorf("%s: output %s is not in the contract", c.Dir, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckValues compares output values that carry no type or sensitivity, such as the outputs of a schematics
+// workspace, with the contract.
+func (c Contract) CheckValues(values map[string]any, gen2 bool) error {
+	var errs []error
+	for _, output := range c.Outputs {
+		value, ok := values[output.Name]
+		if gen2 && output.NullOnGen2 {
+			if ok && value != nil {
+				errs = append(errs, fmt.Errorf("%s: output %s is not null on gen2", c.Dir, output.Name))
+			}
+			continue
+		}
+		if ok && value != nil && valueShape(value) != output.Shape {
+			errs = append(errs, fmt.Errorf("%s: output %s is a %T, want a %s", c.Dir, output.Name, value, output.Shape))
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		if _, ok := c.Output(name); !ok {
+			errs = append(errs, fmt.Errorf("%s: output %s is not in the contract", c.Dir, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// typeShape returns the shape of a Terraform type in its JSON form, such as "string" or ["list","string"]
+func typeShape(src json.RawMessage) (Shape, error) {
+	var kind string
+	if err := json.Unmarshal(src, &kind); err != nil {
+		var complex []json.RawMessage
+		if err := json.Unmarshal(src, &complex); err != nil || len(complex) == 0 {
+			return "", fmt.Errorf("unexpected type %s", src)
+		}
+		if err := json.Unmarshal(complex[0], &kind); err != nil {
+			return "", fmt.Errorf("unexpected type %s", src)
+		}
+	}
+	switch kind {
+	case "string":
+		return String, nil
+	case "number":
+		return Number, nil
+	case "bool":
+		return Bool, nil
+	case "list", "set", "tuple":
+		return List, nil
+	case "map", "object":
+		return Object, nil
+	default:
+		return "", fmt.Errorf("unexpected type %s", src)
+	}
+}
+
+func valueShape(value any) Shape {
+	switch value.(type) {
+	case string:
+		return String
+	case float64, float32, int, int64, json.Number:
+		return Number
+	case bool:
+		return Bool
+	case []any:
+		return List
+	case map[string]any:
+		return Object
+	default:
+		return ""
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
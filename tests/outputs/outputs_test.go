@@ -0,0 +1,108 @@
+package outputs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+var testContract = Contract{
+	Dir: "examples/test",
+	Outputs: []Output{
+		{Name: "id", Shape: String},
+		{Name: "port", Shape: Number, NullOnGen2: true},
+		{Name: "credentials", Sensitive: true, Shape: Object},
+		{Name: "rule_ids", Shape: List},
+	},
+}
+
+func TestFor(t *testing.T) {
+	contract, err := For(".")
+	require.NoError(t, err)
+	_, ok := contract.Output("hostname")
+	assert.True(t, ok)
+	_, ok = contract.Output("missing")
+	assert.False(t, ok)
+
+	_, err = For("examples/missing")
+	assert.Error(t, err)
+}
+
+func TestCheckDeclared(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "outputs.tf"), []byte(`
+output "id" {
+  value = module.db.id
+}
+output "port" {
+  value = module.db.port
+}
+output "credentials" {
+  value     = module.db.credentials
+  sensitive = false
+}
+output "extra" {
+  value = 1
+}
+`), 0o644))
+	m, err := tfconfig.LoadModule(dir)
+	require.NoError(t, err)
+
+	declared, err := Declared(m)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"id": false, "port": false, "credentials": false, "extra": false}, declared)
+
+	err = testContract.CheckDeclared(m)
+	require.Error(t, err)
+	assert.Equal(t, `examples/test: output credentials is declared with sensitive = false
+examples/test: output rule_ids is not declared
+examples/test: output extra is not in the contract`, err.Error())
+}
+
+func TestCheck(t *testing.T) {
+	const applied = `{
+  "id": {"sensitive": false, "type": "string", "value": "crn:1"},
+  "port": {"sensitive": false, "type": "number", "value": 31000},
+  "credentials": {"sensitive": true, "type": ["object", {"user": "string"}], "value": {"user": "admin"}},
+  "rule_ids": {"sensitive": false, "type": ["tuple", []], "value": []}
+}`
+	values, err := ParseJSON(applied)
+	require.NoError(t, err)
+	assert.NoError(t, testContract.Check(values, false))
+	assert.EqualError(t, testContract.Check(values, true), "examples/test: output port is not null on gen2")
+
+	delete(values, "port")
+	assert.NoError(t, testContract.Check(values, true), "terraform omits null outputs")
+
+	values, err = ParseJSON(`{
+  "id": {"sensitive": true, "type": ["list", "string"], "value": ["crn:1"]},
+  "credentials": {"sensitive": true, "type": "string", "value": null},
+  "extra": {"sensitive": false, "type": "number", "value": 1}
+}`)
+	require.NoError(t, err)
+	err = testContract.Check(values, false)
+	require.Error(t, err)
+	assert.Equal(t, `examples/test: output id has sensitive = true
+examples/test: output id has type ["list", "string"], want a string
+examples/test: output extra is not in the contract`, err.Error())
+
+	_, err = ParseJSON("not json")
+	assert.Error(t, err)
+}
+
+func TestCheckValues(t *testing.T) {
+	values := map[string]any{"id": "crn:1", "port": float64(31000), "credentials": map[string]any{"user": "admin"}, "rule_ids": []any{}}
+	assert.NoError(t, testContract.CheckValues(values, false))
+	assert.EqualError(t, testContract.CheckValues(values, true), "examples/test: output port is not null on gen2")
+
+	values = map[string]any{"id": 1.0, "port": nil, "extra": true}
+	err := testContract.CheckValues(values, true)
+	require.Error(t, err)
+	assert.Equal(t, `examples/test: output id is a float64, want a string
+examples/test: output extra is not in the contract`, err.Error())
+}
@@ -0,0 +1,182 @@
+// Package parity compares the `module "elasticsearch"` call in each deployable architecture under solutions/
+// with the root module it calls, and the deployable architectures with each other.
+package parity
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// Kind is the kind of a Finding.
+type Kind string
+
+const (
+	// Unwired is a root input that a solution does not set, so the root default always applies.
+	Unwired Kind = "unwired"
+	// DefaultMismatch is a root input passed straight through from a solution variable with a different default.
+	DefaultMismatch Kind = "default mismatch"
+	// DescriptionMismatch is a variable declared by two solutions with different descriptions.
+	DescriptionMismatch Kind = "description mismatch"
+)
+
+// Finding is a single difference. Variable is the root input for Unwired and DefaultMismatch, and the solution
+// variable for DescriptionMismatch.
+type Finding struct {
+	Kind     Kind
+	Solution string
+	Variable string
+	Detail   string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", f.Solution, f.Kind, f.Variable, f.Detail)
+}
+
+// Solution is a deployable architecture and the name of the block that calls the root module.
+type Solution struct {
+	Name   string
+	Module *tfconfig.Module
+	Call   string
+}
+
+// Load loads solutions/<name> for each name from the repository root, calling `module "elasticsearch"`.
+func Load(repo string, names ...string) ([]Solution, error) {
+	var solutions []Solution
+	for _, name := range names {
+		m, err := tfconfig.LoadModule(repo + "/solutions/" + name)
+		if err != nil {
+			return nil, err
+		}
+		solutions = append(solutions, Solution{Name: name, Module: m, Call: "elasticsearch"})
+	}
+	return solutions, nil
+}
+
+// Argument is how a solution sets a root input.
+type Argument struct {
+	// Source is the expression in the module call
+	Source string
+	// Variable is the solution variable when the expression is just `var.<name>`
+	Variable string
+}
+
+// Wiring returns the arguments of the solution's module call, by root input.
+func Wiring(root *tfconfig.Module, s Solution) (map[string]Argument, error) {
+	block, err := s.Module.Block("module", s.Call)
+	if err != nil {
+		return nil, err
+	}
+	wiring := map[string]Argument{}
+	for name, attr := range block.Body.Attributes {
+		if _, ok := root.Variables[name]; !ok {
+			continue // meta-arguments such as count and source
+		}
+		argument := Argument{Source: s.Module.Source(attr.Expr)}
+		if traversal, ok := attr.Expr.(*hclsyntax.ScopeTraversalExpr); ok && traversal.Traversal.RootName() == "var" && len(traversal.Traversal) == 2 {
+			if step, ok := traversal.Traversal[1].(hcl.TraverseAttr); ok {
+				argument.Variable = step.Name
+			}
+		}
+		wiring[name] = argument
+	}
+	return wiring, nil
+}
+
+// Check returns every finding for the solutions, sorted.
+func Check(root *tfconfig.Module, solutions []Solution) ([]Finding, error) {
+	var findings []Finding
+	for _, s := range solutions {
+		wiring, err := Wiring(root, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		for _, name := range root.VariableOrder {
+			argument, ok := wiring[name]
+			if !ok {
+				findings = append(findings, Finding{Kind: Unwired, Solution: s.Name, Variable: name, Detail: "not set in the module call"})
+				continue
+			}
+			if argument.Variable == "" {
+				continue
+			}
+			variable, ok := s.Module.Variables[argument.Variable]
+			if !ok {
+				return nil, fmt.Errorf("%s: %s is set from undeclared variable %q", s.Name, name, argument.Variable)
+			}
+			if want, got := root.Variables[name].Default, variable.Default; !defaultsEqual(want, got) {
+				findings = append(findings, Finding{
+					Kind:     DefaultMismatch,
+					Solution: s.Name,
+					Variable: name,
+					Detail:   fmt.Sprintf("var.%s defaults to %s, the root module to %s", argument.Variable, render(got), render(want)),
+				})
+			}
+		}
+	}
+
+	for i, a := range solutions {
+		for _, b := range solutions[i+1:] {
+			for _, name := range a.Module.VariableOrder {
+				other, ok := b.Module.Variables[name]
+				if !ok {
+					continue
+				}
+				if normalize(a.Module.Variables[name].Description, a.Name) != normalize(other.Description, b.Name) {
+					findings = append(findings, Finding{
+						Kind:     DescriptionMismatch,
+						Solution: a.Name + "," + b.Name,
+						Variable: name,
+						Detail:   fmt.Sprintf("%q != %q", a.Module.Variables[name].Description, other.Description),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return slices.Compare(
+			[]string{findings[i].Solution, string(findings[i].Kind), findings[i].Variable},
+			[]string{findings[j].Solution, string(findings[j].Kind), findings[j].Variable},
+		) < 0
+	})
+	return findings, nil
+}
+
+// normalize replaces links into the solution's own directory, so the same documentation link in two solutions compares equal
+func normalize(description, solution string) string {
+	return strings.ReplaceAll(description, "/solutions/"+solution+"/", "/solutions/<solution>/")
+}
+
+// defaultsEqual compares defaults by value, ignoring the type of nulls and of required variables
+func defaultsEqual(a, b cty.Value) bool {
+	if a == cty.NilVal || b == cty.NilVal {
+		return a == b
+	}
+	if a.IsNull() || b.IsNull() {
+		return a.IsNull() == b.IsNull()
+	}
+	return a.Equals(b).True()
+}
+
+func render(value cty.Value) string {
+	if value == cty.NilVal {
+		return "no default"
+	}
+	if value.IsNull() {
+		return "null"
+	}
+	src, err := ctyjson.Marshal(value, value.Type())
+	if err != nil {
+		return value.GoString()
+	}
+	return string(src)
+}
@@ -0,0 +1,121 @@
+package parity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+func writeModule(t *testing.T, dir string, src string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(src), 0o644))
+}
+
+func TestCheck(t *testing.T) {
+	repo := t.TempDir()
+	writeModule(t, repo, `
+variable "plan" {
+  type    = string
+  default = "standard"
+}
+variable "size" {
+  type    = number
+  default = 5
+}
+variable "tags" {
+  type    = list(string)
+  default = []
+}
+variable "flavor" {
+  type    = string
+  default = null
+}
+`)
+	writeModule(t, filepath.Join(repo, "solutions", "classic"), `
+variable "plan" {
+  type        = string
+  default     = "standard"
+  description = "The plan. [Learn more](https://example.com/solutions/classic/README.md)"
+}
+variable "member_size" {
+  type        = number
+  default     = 10
+  description = "The size."
+}
+variable "flavor" {
+  type    = string
+  default = null
+}
+module "elasticsearch" {
+  count  = 1
+  source = "../.."
+  plan   = var.plan
+  size   = var.member_size
+  flavor = var.flavor
+}
+`)
+	writeModule(t, filepath.Join(repo, "solutions", "gen2"), `
+variable "plan" {
+  type        = string
+  default     = "gen2"
+  description = "The plan. [Learn more](https://example.com/solutions/gen2/README.md)"
+}
+variable "member_size" {
+  type        = number
+  default     = 5
+  description = "The size of each member."
+}
+module "elasticsearch" {
+  source = "../.."
+  plan   = "${var.plan}-gen2"
+  size   = var.member_size
+  tags   = []
+  flavor = null
+}
+`)
+
+	root, err := tfconfig.LoadModule(repo)
+	require.NoError(t, err)
+	solutions, err := Load(repo, "classic", "gen2")
+	require.NoError(t, err)
+
+	wiring, err := Wiring(root, solutions[1])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Argument{
+		"plan":   {Source: `"${var.plan}-gen2"`},
+		"size":   {Source: "var.member_size", Variable: "member_size"},
+		"tags":   {Source: "[]"},
+		"flavor": {Source: "null"},
+	}, wiring)
+
+	findings, err := Check(root, solutions)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Kind: DefaultMismatch, Solution: "classic", Variable: "size", Detail: "var.member_size defaults to 10, the root module to 5"},
+		{Kind: Unwired, Solution: "classic", Variable: "tags", Detail: "not set in the module call"},
+		{Kind: DescriptionMismatch, Solution: "classic,gen2", Variable: "member_size", Detail: `"The size." != "The size of each member."`},
+	}, findings)
+}
+
+func TestCheckUndeclaredVariable(t *testing.T) {
+	repo := t.TempDir()
+	writeModule(t, repo, `variable "plan" {}`)
+	writeModule(t, filepath.Join(repo, "solutions", "da"), `
+module "elasticsearch" {
+  source = "../.."
+  plan   = var.missing
+}
+`)
+	root, err := tfconfig.LoadModule(repo)
+	require.NoError(t, err)
+	solutions, err := Load(repo, "da")
+	require.NoError(t, err)
+	_, err = Check(root, solutions)
+	assert.ErrorContains(t, err, `undeclared variable "missing"`)
+}
@@ -0,0 +1,289 @@
+// Package plancheck makes assertions about what a Terraform plan will create, update or replace, using the JSON
+// representation of a saved plan (`terraform show -json`).
+package plancheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Show plans into a temporary plan file and returns the parsed `terraform show -json` output for it.
+// The Terraform working directory must already be initialised.
+func Show(t *testing.T, ctx context.Context, options *terraform.Options) (*terraform.PlanStruct, error) {
+	previous := options.PlanFilePath
+	options.PlanFilePath = filepath.Join(t.TempDir(), "plan.tfplan")
+	defer func() { options.PlanFilePath = previous }()
+
+	if _, err := terraform.PlanContextE(t, ctx, options); err != nil {
+		return nil, err
+	}
+	return terraform.ShowWithStructContextE(t, ctx, options)
+}
+
+var moduleAddress = regexp.MustCompile(`(^|\.)module\.[^.\[]+(\[[^\]]+\])?$`)
+
+// IsModule reports whether address refers to a module, such as module.cbr_rule or module.elasticsearch[0].module.cbr_rule,
+// rather than to a resource.
+func IsModule(address string) bool {
+	return moduleAddress.MatchString(address)
+}
+
+// remains reports whether the instance still exists after the plan is applied
+func remains(change *tfjson.ResourceChange) bool {
+	return change.Change == nil || !change.Change.Actions.Delete()
+}
+
+// Count returns how many instances of the resource or module at address remain after the plan is applied.
+// An address without an index counts every instance, so module.cbr_rule counts module.cbr_rule[0] and module.cbr_rule[1].
+// Module instances are counted from the resources they plan, so a module instance without resources is not counted.
+func Count(plan *terraform.PlanStruct, address string) int {
+	instance := regexp.MustCompile(`^` + regexp.QuoteMeta(address) + `(\[[^\]]+\])?`)
+	seen := map[string]bool{}
+	for _, change := range plan.ResourceChangesMap {
+		if !remains(change) {
+			continue
+		}
+		if IsModule(address) {
+			prefix := instance.FindString(change.ModuleAddress)
+			if prefix != "" && (len(prefix) == len(change.ModuleAddress) || change.ModuleAddress[len(prefix)] == '.') {
+				seen[prefix] = true
+			}
+			continue
+		}
+		if match := instance.FindString(change.Address); match != "" && len(match) == len(change.Address) {
+			seen[change.Address] = true
+		}
+	}
+	return len(seen)
+}
+
+// Replacements returns the addresses of the resources the plan replaces, sorted.
+func Replacements(plan *terraform.PlanStruct) []string {
+	var replaced []string
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change != nil && change.Change.Actions.Replace() {
+			replaced = append(replaced, address)
+		}
+	}
+	sort.Strings(replaced)
+	return replaced
+}
+
+// Attribute returns the planned value at path in the resource change, for example "plan" or
+// "group.0.memory.0.allocation_mb". known is false if the value is only known after apply.
+func Attribute(change *tfjson.ResourceChange, path string) (value interface{}, known bool, err error) {
+	if change.Change == nil {
+		return nil, false, fmt.Errorf("%s has no planned change", change.Address)
+	}
+	if unknown, _ := lookup(change.Change.AfterUnknown, path); unknown == true {
+		return nil, false, nil
+	}
+	value, err = lookup(change.Change.After, path)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", change.Address, err)
+	}
+	return value, true, nil
+}
+
+func lookup(value interface{}, path string) (interface{}, error) {
+	for _, step := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[step]
+			if !ok {
+				return nil, fmt.Errorf("attribute %q not found in %s", step, path)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(step)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("index %q out of range in %s", step, path)
+			}
+			value = v[index]
+		default:
+			return nil, fmt.Errorf("cannot look up %q in %s", step, path)
+		}
+	}
+	return value, nil
+}
+
+// normalize converts a Go value to the types encoding/json decodes into, so 1 compares equal to float64(1)
+func normalize(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	err = json.Unmarshal(encoded, &decoded)
+	return decoded, err
+}
+
+// TestingT is the subset of testing.T the assertions report to.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+type helper interface {
+	Helper()
+}
+
+// Checker reports failed assertions about a plan to a test, for example
+//
+//	plancheck.Assert(t, plan).
+//		NoReplacements().
+//		Count("module.elasticsearch[0].module.cbr_rule", 1).
+//		Resource("module.elasticsearch[0].ibm_database.elasticsearch").Creates().Has("plan", "platinum")
+type Checker struct {
+	t    TestingT
+	plan *terraform.PlanStruct
+}
+
+// Assert starts assertions about a plan.
+func Assert(t TestingT, plan *terraform.PlanStruct) *Checker {
+	return &Checker{t: t, plan: plan}
+}
+
+func (c *Checker) errorf(format string, args ...interface{}) {
+	if h, ok := c.t.(helper); ok {
+		h.Helper()
+	}
+	c.t.Errorf(format, args...)
+}
+
+// Count asserts how many instances of the resource or module at address remain after apply.
+func (c *Checker) Count(address string, want int) *Checker {
+	if got := Count(c.plan, address); got != want {
+		c.errorf("plan has %d instances of %s, expected %d", got, address, want)
+	}
+	return c
+}
+
+// Absent asserts that nothing at address remains after apply.
+func (c *Checker) Absent(address string) *Checker {
+	return c.Count(address, 0)
+}
+
+// NoReplacements asserts that no resource is being replaced.
+func (c *Checker) NoReplacements() *Checker {
+	if replaced := Replacements(c.plan); len(replaced) > 0 {
+		c.errorf("plan replaces %s", strings.Join(replaced, ", "))
+	}
+	return c
+}
+
+// Resource starts assertions about the resource instance at address, which must be in the plan.
+func (c *Checker) Resource(address string) *ResourceChecker {
+	change, ok := c.plan.ResourceChangesMap[address]
+	if !ok {
+		c.errorf("plan has no resource %s", address)
+	}
+	return &ResourceChecker{Checker: c, address: address, change: change}
+}
+
+// ResourceChecker reports failed assertions about a single resource instance. It embeds the Checker of the plan,
+// so assertions about other resources can follow in the same chain.
+type ResourceChecker struct {
+	*Checker
+	address string
+	change  *tfjson.ResourceChange
+}
+
+// Creates asserts that the resource is created, and not replaced.
+func (r *ResourceChecker) Creates() *ResourceChecker {
+	if r.change != nil && (r.change.Change == nil || !r.change.Change.Actions.Create()) {
+		r.errorf("%s is not created by the plan: %v", r.address, actions(r.change))
+	}
+	return r
+}
+
+// UpdatesInPlace asserts that the resource is updated without being replaced.
+func (r *ResourceChecker) UpdatesInPlace() *ResourceChecker {
+	if r.change != nil && (r.change.Change == nil || !r.change.Change.Actions.Update()) {
+		r.errorf("%s is not updated in place by the plan: %v", r.address, actions(r.change))
+	}
+	return r
+}
+
+// Has asserts the planned value at path, for example Has("plan", "platinum"). Use nil for a value that is planned
+// to be null.
+func (r *ResourceChecker) Has(path string, want interface{}) *ResourceChecker {
+	if r.change == nil {
+		return r
+	}
+	got, known, err := Attribute(r.change, path)
+	if err != nil {
+		r.errorf("%v", err)
+		return r
+	}
+	if !known {
+		r.errorf("%s: %s is only known after apply, expected %v", r.address, path, want)
+		return r
+	}
+	expected, err := normalize(want)
+	if err != nil {
+		r.errorf("%s: cannot compare %s with %v: %v", r.address, path, want, err)
+		return r
+	}
+	if !reflect.DeepEqual(got, expected) {
+		r.errorf("%s: %s is %v, expected %v", r.address, path, got, want)
+	}
+	return r
+}
+
+// Len asserts the number of elements of the planned list at path, for example Len("group", 1) for a resource with
+// a single group block. A block that is not sent is planned as an empty list or null, which has no elements.
+func (r *ResourceChecker) Len(path string, want int) *ResourceChecker {
+	if r.change == nil {
+		return r
+	}
+	got, known, err := Attribute(r.change, path)
+	if err != nil {
+		r.errorf("%v", err)
+		return r
+	}
+	if !known {
+		r.errorf("%s: %s is only known after apply, expected %d elements", r.address, path, want)
+		return r
+	}
+	list, ok := got.([]interface{})
+	if got != nil && !ok {
+		r.errorf("%s: %s is %v, not a list", r.address, path, got)
+		return r
+	}
+	if len(list) != want {
+		r.errorf("%s: %s has %d elements, expected %d", r.address, path, len(list), want)
+	}
+	return r
+}
+
+// Unknown asserts that the value at path is only known after apply.
+func (r *ResourceChecker) Unknown(path string) *ResourceChecker {
+	if r.change == nil {
+		return r
+	}
+	got, known, err := Attribute(r.change, path)
+	if err != nil {
+		r.errorf("%v", err)
+	} else if known {
+		r.errorf("%s: %s is planned as %v, expected it to be known after apply", r.address, path, got)
+	}
+	return r
+}
+
+func actions(change *tfjson.ResourceChange) tfjson.Actions {
+	if change.Change == nil {
+		return nil
+	}
+	return change.Change.Actions
+}
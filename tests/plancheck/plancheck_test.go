@@ -0,0 +1,126 @@
+package plancheck
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const database = "module.elasticsearch[0].ibm_database.elasticsearch"
+
+func loadPlan(t *testing.T) *terraform.PlanStruct {
+	t.Helper()
+	raw, err := os.ReadFile("testdata/plan.json")
+	require.NoError(t, err)
+	plan, err := terraform.ParsePlanJSON(string(raw))
+	require.NoError(t, err)
+	return plan
+}
+
+// recorder collects failed assertions instead of failing the test
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestIsModule(t *testing.T) {
+	assert.True(t, IsModule("module.cbr_rule"))
+	assert.True(t, IsModule("module.elasticsearch[0].module.cbr_rule[1]"))
+	assert.False(t, IsModule("module.elasticsearch[0].ibm_database.elasticsearch"))
+	assert.False(t, IsModule("time_sleep.wait"))
+}
+
+func TestCount(t *testing.T) {
+	plan := loadPlan(t)
+	tests := map[string]int{
+		"module.elasticsearch":                    1,
+		"module.elasticsearch[0]":                 1,
+		"module.elasticsearch[0].module.cbr_rule": 1, // the second instance is deleted
+		database:                         1,
+		"random_password.admin_password": 1,
+		"time_sleep.wait":                2,
+		`time_sleep.wait["a"]`:           1,
+		"time_sleep.wai":                 0,
+		"module.elastic":                 0,
+		"module.kms":                     0,
+	}
+	for address, want := range tests {
+		assert.Equal(t, want, Count(plan, address), address)
+	}
+}
+
+func TestReplacements(t *testing.T) {
+	assert.Equal(t, []string{"random_password.admin_password[0]"}, Replacements(loadPlan(t)))
+}
+
+func TestAttribute(t *testing.T) {
+	change := loadPlan(t).ResourceChangesMap[database]
+
+	value, known, err := Attribute(change, "plan")
+	require.NoError(t, err)
+	assert.True(t, known)
+	assert.Equal(t, "platinum", value)
+
+	value, known, err = Attribute(change, "group.0.members.0.allocation_count")
+	require.NoError(t, err)
+	assert.True(t, known)
+	assert.Equal(t, float64(3), value)
+
+	_, known, err = Attribute(change, "crn")
+	require.NoError(t, err)
+	assert.False(t, known)
+
+	for _, path := range []string{"missing", "group.1", "group.x", "plan.0"} {
+		_, _, err = Attribute(change, path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestCheckerPasses(t *testing.T) {
+	r := &recorder{}
+	Assert(r, loadPlan(t)).
+		Count("module.elasticsearch[0].module.cbr_rule", 1).
+		Absent("module.kms").
+		Resource(database).Creates().Has("plan", "platinum").Has("group.0.members.0.allocation_count", 3).Has("key_protect_key", nil).Unknown("crn").
+		Len("group", 1).Len("group.0.members", 1).Len("key_protect_key", 0).
+		Resource(`time_sleep.wait["a"]`).UpdatesInPlace()
+	assert.Empty(t, r.errors)
+}
+
+func TestCheckerFailures(t *testing.T) {
+	plan := loadPlan(t)
+	tests := []struct {
+		name  string
+		check func(c *Checker)
+		want  string
+	}{
+		{"count", func(c *Checker) { c.Count("time_sleep.wait", 1) }, "plan has 2 instances of time_sleep.wait, expected 1"},
+		{"absent", func(c *Checker) { c.Absent(database) }, "expected 0"},
+		{"replacement", func(c *Checker) { c.NoReplacements() }, "plan replaces random_password.admin_password[0]"},
+		{"missing resource", func(c *Checker) { c.Resource("ibm_database.other").Creates().Has("plan", "x") }, "plan has no resource ibm_database.other"},
+		{"not created", func(c *Checker) { c.Resource(`time_sleep.wait["b"]`).Creates() }, "is not created by the plan"},
+		{"not updated", func(c *Checker) { c.Resource(database).UpdatesInPlace() }, "is not updated in place"},
+		{"wrong value", func(c *Checker) { c.Resource(database).Has("plan", "enterprise") }, "plan is platinum, expected enterprise"},
+		{"unknown value", func(c *Checker) { c.Resource(database).Has("crn", "crn:1") }, "crn is only known after apply"},
+		{"known value", func(c *Checker) { c.Resource(database).Unknown("version") }, "version is planned as 8.19"},
+		{"missing attribute", func(c *Checker) { c.Resource(database).Has("missing", 1) }, `attribute "missing" not found`},
+		{"length", func(c *Checker) { c.Resource(database).Len("group", 2) }, "group has 1 elements, expected 2"},
+		{"not a list", func(c *Checker) { c.Resource(database).Len("plan", 1) }, "plan is platinum, not a list"},
+		{"unknown length", func(c *Checker) { c.Resource(database).Len("crn", 0) }, "crn is only known after apply, expected 0 elements"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{}
+			tc.check(Assert(r, plan))
+			require.Len(t, r.errors, 1)
+			assert.Contains(t, r.errors[0], tc.want)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package replay records the IBM Cloud catalog lookups made by the tests to JSON fixtures and
+// replays them, so the version logic and plan tests can run without an API key or network access.
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ModeEnvVar selects how catalog lookups are served: "record", "replay" or unset for live lookups only.
+const ModeEnvVar = "CLOUDINFO_FIXTURE_MODE"
+
+// Mode is the fixture mode.
+type Mode string
+
+const (
+	Live   Mode = ""
+	Record Mode = "record"
+	Replay Mode = "replay"
+)
+
+// ModeFromEnv returns the mode set in ModeEnvVar.
+func ModeFromEnv() (Mode, error) {
+	mode := Mode(strings.ToLower(strings.TrimSpace(os.Getenv(ModeEnvVar))))
+	switch mode {
+	case Live, Record, Replay:
+		return mode, nil
+	default:
+		return Live, fmt.Errorf("invalid %s value %q: must be %q, %q or empty", ModeEnvVar, mode, Record, Replay)
+	}
+}
+
+// Offline reports whether the tests must not reach the live catalog.
+func Offline() bool {
+	mode, err := ModeFromEnv()
+	return err == nil && mode == Replay
+}
+
+// Catalog looks up the Elasticsearch versions offered per region.
+type Catalog interface {
+	// ClassicVersions returns the versions offered for a classic ICD type (for example "elasticsearch") in a region.
+	ClassicVersions(region string, icdType string) ([]string, error)
+	// Gen2Versions returns the versions offered for a gen2 service plan in a region.
+	Gen2Versions(service string, plan string, region string) ([]string, error)
+}
+
+// New returns a catalog for the mode. Live returns the live catalog unchanged, Record wraps it and
+// saves every lookup to dir, and Replay serves lookups from dir without using the live catalog.
+func New(mode Mode, live Catalog, dir string) (Catalog, error) {
+	switch mode {
+	case Live:
+		return live, nil
+	case Record:
+		return &recorder{live: live, dir: dir}, nil
+	case Replay:
+		return &replayer{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown fixture mode %q", mode)
+	}
+}
+
+// FromEnv is New using the mode set in ModeEnvVar.
+func FromEnv(live Catalog, dir string) (Catalog, error) {
+	mode, err := ModeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return New(mode, live, dir)
+}
+
+// ErrNotRecorded is returned when replaying a lookup that was never recorded.
+var ErrNotRecorded = errors.New("catalog lookup not recorded")
+
+// fixture is the on-disk format of a single recorded lookup.
+type fixture struct {
+	Versions []string `json:"versions"`
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+func classicFixture(region string, icdType string) string {
+	return fixtureName("classic", icdType, region)
+}
+
+func gen2Fixture(service string, plan string, region string) string {
+	return fixtureName("gen2", service, plan, region)
+}
+
+func fixtureName(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = unsafeChars.ReplaceAllString(part, "-")
+	}
+	return strings.Join(parts, "_") + ".json"
+}
+
+type recorder struct {
+	live Catalog
+	dir  string
+}
+
+func (r *recorder) ClassicVersions(region string, icdType string) ([]string, error) {
+	versions, err := r.live.ClassicVersions(region, icdType)
+	if err != nil {
+		return nil, err
+	}
+	return versions, r.save(classicFixture(region, icdType), versions)
+}
+
+func (r *recorder) Gen2Versions(service string, plan string, region string) ([]string, error) {
+	versions, err := r.live.Gen2Versions(service, plan, region)
+	if err != nil {
+		return nil, err
+	}
+	return versions, r.save(gen2Fixture(service, plan, region), versions)
+}
+
+func (r *recorder) save(name string, versions []string) error {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("creating fixture directory: %w", err)
+	}
+	data, err := json.MarshalIndent(fixture{Versions: versions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("recording fixture %s: %w", name, err)
+	}
+	return nil
+}
+
+type replayer struct {
+	dir string
+}
+
+func (r *replayer) ClassicVersions(region string, icdType string) ([]string, error) {
+	return r.load(classicFixture(region, icdType))
+}
+
+func (r *replayer) Gen2Versions(service string, plan string, region string) ([]string, error) {
+	return r.load(gen2Fixture(service, plan, region))
+}
+
+func (r *replayer) load(name string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: no %s in %s, run the tests once with %s=%s to record it", ErrNotRecorded, name, r.dir, ModeEnvVar, Record)
+		}
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", name, err)
+	}
+	return f.Versions, nil
+}
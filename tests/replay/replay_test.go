@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+type fakeCatalog struct {
+	calls int
+	err   error
+}
+
+func (f *fakeCatalog) ClassicVersions(region string, icdType string) ([]string, error) {
+	f.calls++
+	return []string{"8.15", region, icdType}, f.err
+}
+
+func (f *fakeCatalog) Gen2Versions(service string, plan string, region string) ([]string, error) {
+	f.calls++
+	return []string{"8.19", service, plan, region}, f.err
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	live := &fakeCatalog{}
+
+	recording, err := New(Record, live, dir)
+	require.NoError(t, err)
+	classic, err := recording.ClassicVersions("us-south", "elasticsearch")
+	require.NoError(t, err)
+	gen2, err := recording.Gen2Versions("databases-for-elasticsearch", "enterprise-gen2", "eu-de")
+	require.NoError(t, err)
+	assert.Equal(t, 2, live.calls)
+	assert.FileExists(t, filepath.Join(dir, "classic_elasticsearch_us-south.json"))
+	assert.FileExists(t, filepath.Join(dir, "gen2_databases-for-elasticsearch_enterprise-gen2_eu-de.json"))
+
+	replaying, err := New(Replay, nil, dir)
+	require.NoError(t, err)
+	replayedClassic, err := replaying.ClassicVersions("us-south", "elasticsearch")
+	require.NoError(t, err)
+	replayedGen2, err := replaying.Gen2Versions("databases-for-elasticsearch", "enterprise-gen2", "eu-de")
+	require.NoError(t, err)
+	assert.Equal(t, classic, replayedClassic)
+	assert.Equal(t, gen2, replayedGen2)
+	assert.Equal(t, 2, live.calls, "replay must not call the live catalog")
+}
+
+func TestRecordDoesNotSaveFailedLookups(t *testing.T) {
+	dir := t.TempDir()
+	recording, err := New(Record, &fakeCatalog{err: errors.New("unauthorized")}, dir)
+	require.NoError(t, err)
+
+	_, err = recording.ClassicVersions("us-south", "elasticsearch")
+	assert.EqualError(t, err, "unauthorized")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	replaying, err := New(Replay, nil, t.TempDir())
+	require.NoError(t, err)
+
+	_, err = replaying.ClassicVersions("jp-tok", "elasticsearch")
+	require.ErrorIs(t, err, ErrNotRecorded)
+	assert.Contains(t, err.Error(), "classic_elasticsearch_jp-tok.json")
+	assert.Contains(t, err.Error(), ModeEnvVar)
+}
+
+func TestLiveModeReturnsLiveCatalog(t *testing.T) {
+	live := &fakeCatalog{}
+	catalog, err := New(Live, live, t.TempDir())
+	require.NoError(t, err)
+	assert.Same(t, live, catalog)
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(ModeEnvVar, "Replay")
+	mode, err := ModeFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Replay, mode)
+	assert.True(t, Offline())
+
+	t.Setenv(ModeEnvVar, "sometimes")
+	_, err = ModeFromEnv()
+	assert.Error(t, err)
+	assert.False(t, Offline())
+}
+
+// The committed fixtures must stay parseable by the version logic that consumes them
+func TestCommittedFixturesParse(t *testing.T) {
+	files, err := filepath.Glob("../testdata/cloudinfo/*.json")
+	require.NoError(t, err)
+
+	replaying, err := New(Replay, nil, "../testdata/cloudinfo")
+	require.NoError(t, err)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := replaying.(*replayer).load(filepath.Base(file))
+			require.NoError(t, err)
+			require.NotEmpty(t, f)
+			_, err = versions.ParseAll(f)
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Package secretsmanager reads the secrets that the solutions store in an existing Secrets Manager instance, with
+// the REST API of the instance and an IAM token for an API key.
+package secretsmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// iamURL is the IAM endpoint that exchanges API keys for tokens
+const iamURL = "https://iam.cloud.ibm.com/identity/token"
+
+// Client reads the secrets of an instance.
+type Client struct {
+	endpoint string
+	iamURL   string
+	apiKey   string
+	http     *http.Client
+}
+
+// New returns a client for the public endpoint of the instance with the CRN, authenticated with the API key.
+func New(instanceCRN string, apiKey string) (*Client, error) {
+	// crn:v1:bluemix:public:secrets-manager:<region>:a/<account>:<guid>::
+	parts := strings.Split(instanceCRN, ":")
+	if len(parts) != 10 || parts[0] != "crn" || parts[4] != "secrets-manager" || parts[5] == "" || parts[7] == "" {
+		return nil, fmt.Errorf("%q is not the CRN of a Secrets Manager instance", instanceCRN)
+	}
+	return &Client{
+		endpoint: fmt.Sprintf("https://%s.%s.secrets-manager.appdomain.cloud", parts[7], parts[5]),
+		iamURL:   iamURL,
+		apiKey:   apiKey,
+		http:     http.DefaultClient,
+	}, nil
+}
+
+// ArbitrarySecret returns the payload of the arbitrary secret with the name in the secret group with the name.
+func (c *Client) ArbitrarySecret(ctx context.Context, groupName string, secretName string) (string, error) {
+	token, err := c.token(ctx)
+	if err != nil {
+		return "", err
+	}
+	var groups struct {
+		SecretGroups []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"secret_groups"`
+	}
+	if err := c.get(ctx, token, "/api/v2/secret_groups", &groups); err != nil {
+		return "", err
+	}
+	groupID := ""
+	for _, group := range groups.SecretGroups {
+		if group.Name == groupName {
+			groupID = group.ID
+		}
+	}
+	if groupID == "" {
+		return "", fmt.Errorf("there is no secret group %s", groupName)
+	}
+
+	var secret struct {
+		Payload *string `json:"payload"`
+	}
+	if err := c.get(ctx, token, "/api/v2/secret_groups/"+url.PathEscape(groupID)+"/secret_types/arbitrary/secret_names/"+url.PathEscape(secretName), &secret); err != nil {
+		return "", fmt.Errorf("reading secret %s of group %s: %w", secretName, groupName, err)
+	}
+	if secret.Payload == nil {
+		return "", fmt.Errorf("secret %s of group %s has no payload", secretName, groupName)
+	}
+	return *secret.Payload, nil
+}
+
+// token exchanges the API key for an IAM access token
+func (c *Client) token(ctx context.Context) (string, error) {
+	form := url.Values{"grant_type": {"urn:ibm:params:oauth:grant-type:apikey"}, "apikey": {c.apiKey}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.iamURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("getting an IAM token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+func (c *Client) get(ctx context.Context, token string, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	return c.do(req, out)
+}
+
+// do sends the request and decodes a 200 response into out. The body of other responses is in the error, except
+// for the IAM endpoint, whose errors could echo the API key.
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if req.URL.String() == c.iamURL {
+			return fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+		}
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%s %s: decoding the response: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
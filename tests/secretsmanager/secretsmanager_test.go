@@ -0,0 +1,82 @@
+package secretsmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New("crn:v1:bluemix:public:secrets-manager:us-south:a/abc123:0c1a2b3d-0000-4000-8000-000000000000::", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "https://0c1a2b3d-0000-4000-8000-000000000000.us-south.secrets-manager.appdomain.cloud", c.endpoint)
+
+	for _, crn := range []string{"", "crn:v1:bluemix:public:kms:us-south:a/abc123:0c1a2b3d::", "crn:v1:bluemix:public:secrets-manager::a/abc123:0c1a2b3d::"} {
+		_, err := New(crn, "key")
+		assert.ErrorContains(t, err, "is not the CRN of a Secrets Manager instance", crn)
+	}
+}
+
+// serve starts a stand-in for IAM and an instance with the secret kibana-app-password in the group admin-secrets,
+// and returns a client for it
+func serve(t *testing.T) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /identity/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("apikey") != "key" || r.FormValue("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errorMessage": "Provided API key could not be found: ` + r.FormValue("apikey") + `"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token": "token"}`))
+	})
+	authorized := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/v2/secret_groups", authorized(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"secret_groups": []any{
+			map[string]any{"id": "default", "name": "default"},
+			map[string]any{"id": "g1", "name": "admin-secrets"},
+		}})
+	}))
+	mux.HandleFunc("GET /api/v2/secret_groups/{group}/secret_types/arbitrary/secret_names/{name}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("group") != "g1" || r.PathValue("name") != "kibana-app-password" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": [{"code": "secret_not_found"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name": "kibana-app-password", "secret_type": "arbitrary", "payload": "p4ssw0rd"}`)) // pragma: allowlist secret
+	}))
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+	return &Client{endpoint: server.URL, iamURL: server.URL + "/identity/token", apiKey: "key", http: server.Client()}
+}
+
+func TestArbitrarySecret(t *testing.T) {
+	c := serve(t)
+	payload, err := c.ArbitrarySecret(context.Background(), "admin-secrets", "kibana-app-password")
+	require.NoError(t, err)
+	assert.Equal(t, "p4ssw0rd", payload) // pragma: allowlist secret
+
+	_, err = c.ArbitrarySecret(context.Background(), "other-secrets", "kibana-app-password")
+	assert.EqualError(t, err, "there is no secret group other-secrets")
+
+	_, err = c.ArbitrarySecret(context.Background(), "admin-secrets", "kibana-system-password")
+	assert.ErrorContains(t, err, "reading secret kibana-system-password of group admin-secrets: GET /api/v2/secret_groups/g1/secret_types/arbitrary/secret_names/kibana-system-password: 404 Not Found: {\"errors\": [{\"code\": \"secret_not_found\"}]}")
+
+	c.apiKey = "wrong"
+	_, err = c.ArbitrarySecret(context.Background(), "admin-secrets", "kibana-app-password")
+	assert.EqualError(t, err, "getting an IAM token: POST /identity/token: 400 Bad Request")
+	assert.NotContains(t, err.Error(), "wrong", "the API key is not in the error")
+}
@@ -0,0 +1,161 @@
+// Package smoke checks that a deployed instance serves requests: the cluster is healthy, runs the deployed
+// Elasticsearch version and can create, fill, search and delete an index.
+package smoke
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/outputs"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+// Options configure Verify.
+type Options struct {
+	// Version is the elasticsearch_version the instance was deployed with, such as "8.19". The cluster may report a
+	// patch version of it.
+	Version string
+	// Status is the least healthy cluster status that passes, "green" when empty
+	Status string
+	// HealthTimeout is how long to wait for the status, one minute when zero
+	HealthTimeout time.Duration
+	// Index is the index of the round trip, which must not exist. A unique name is used when empty.
+	Index string
+	// Documents is the number of documents of the round trip, 3 when zero
+	Documents int
+}
+
+func (o Options) withDefaults() Options {
+	if o.Status == "" {
+		o.Status = "green"
+	}
+	if o.HealthTimeout == 0 {
+		o.HealthTimeout = time.Minute
+	}
+	if o.Index == "" {
+		o.Index = fmt.Sprintf("smoke-test-%d", time.Now().UnixNano())
+	}
+	if o.Documents == 0 {
+		o.Documents = 3
+	}
+	return o
+}
+
+// Verify checks the version and health of the cluster and runs the index round trip. The index is deleted even if
+// a step of the round trip fails.
+func Verify(ctx context.Context, client *elasticsearch.Client, opts Options) error {
+	opts = opts.withDefaults()
+	want, err := versions.Parse(opts.Version)
+	if err != nil {
+		return err
+	}
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the cluster version: %w", err)
+	}
+	got, err := versions.Parse(info.Version.Number)
+	if err != nil {
+		return fmt.Errorf("cluster version: %w", err)
+	}
+	if !want.Matches(got) {
+		return fmt.Errorf("the cluster runs version %s, expected %s", got, want)
+	}
+
+	health, err := client.Health(ctx, opts.Status, opts.HealthTimeout)
+	if err != nil {
+		return fmt.Errorf("reading the cluster health: %w", err)
+	}
+	if health.TimedOut {
+		return fmt.Errorf("the cluster status is %s after %s, expected %s", health.Status, opts.HealthTimeout, opts.Status)
+	}
+
+	return roundTrip(ctx, client, opts.Index, opts.Documents)
+}
+
+func roundTrip(ctx context.Context, client *elasticsearch.Client, index string, documents int) (err error) {
+	mappings := map[string]any{"mappings": map[string]any{"properties": map[string]any{"message": map[string]any{"type": "text"}}}}
+	if err := client.Do(ctx, http.MethodPut, "/"+index, mappings, nil); err != nil {
+		return fmt.Errorf("creating index %s: %w", index, err)
+	}
+	defer func() {
+		if deleteErr := client.Do(ctx, http.MethodDelete, "/"+index, nil, nil); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting index %s: %w", index, deleteErr))
+		}
+	}()
+
+	var bulk elasticsearch.NDJSON
+	for i := range documents {
+		bulk = append(bulk,
+			map[string]any{"index": map[string]any{"_id": fmt.Sprint(i)}},
+			map[string]any{"message": fmt.Sprintf("smoke test document %d", i)},
+		)
+	}
+	var indexed struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := client.Do(ctx, http.MethodPost, "/"+index+"/_bulk?refresh=wait_for", bulk, &indexed); err != nil {
+		return fmt.Errorf("indexing into %s: %w", index, err)
+	}
+	if indexed.Errors || len(indexed.Items) != documents {
+		return fmt.Errorf("indexing into %s: %d of %d documents indexed, errors %t", index, len(indexed.Items), documents, indexed.Errors)
+	}
+
+	query := map[string]any{"query": map[string]any{"match": map[string]any{"message": "smoke"}}}
+	var found struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	if err := client.Do(ctx, http.MethodPost, "/"+index+"/_search", query, &found); err != nil {
+		return fmt.Errorf("searching %s: %w", index, err)
+	}
+	if found.Hits.Total.Value != documents {
+		return fmt.Errorf("searching %s found %d documents, expected %d", index, found.Hits.Total.Value, documents)
+	}
+	return nil
+}
+
+// FromOutputs returns the credentials of the named service credential in the service_credentials_object output.
+// The hostname, port and certificate_base64 outputs are used instead of the host and certificate of the object
+// where a configuration has them; they are null on gen2.
+func FromOutputs(values map[string]outputs.Value, credential string, gen2 bool) (credentials.Credentials, error) {
+	object, ok := values["service_credentials_object"]
+	if !ok {
+		return credentials.Credentials{}, errors.New("no service_credentials_object output")
+	}
+	all, err := credentials.FromServiceCredentialsObject(object.Value, gen2)
+	if err != nil {
+		return credentials.Credentials{}, err
+	}
+	c, ok := all[credential]
+	if !ok {
+		return credentials.Credentials{}, fmt.Errorf("service_credentials_object has no credential %s", credential)
+	}
+
+	for name, target := range map[string]any{"hostname": &c.Hostname, "port": &c.Port, "certificate_base64": &c.CertificateBase64} {
+		value, ok := values[name]
+		if !ok || string(value.Value) == "null" {
+			continue
+		}
+		if gen2 {
+			return credentials.Credentials{}, fmt.Errorf("output %s is not null on gen2", name)
+		}
+		if err := json.Unmarshal(value.Value, target); err != nil {
+			return credentials.Credentials{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,105 @@
+package smoke
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/outputs"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		status  string
+		// index is created beforehand when set
+		index   string
+		fault   *elasticsearchtest.Fault
+		opts    Options
+		wantErr string
+		// wantIndex is whether the smoke index is left afterwards
+		wantIndex bool
+	}{
+		{name: "healthy", opts: Options{Version: "8.19"}},
+		{name: "yellow is enough when asked for", status: "yellow", opts: Options{Version: "8.19", Status: "yellow"}},
+		{name: "other version", version: "8.15.1", opts: Options{Version: "8.19"}, wantErr: "the cluster runs version 8.15.1, expected 8.19"},
+		{name: "not green", status: "yellow", opts: Options{Version: "8.19"}, wantErr: "the cluster status is yellow after 1m0s, expected green"},
+		{name: "index exists", index: "smoke", opts: Options{Version: "8.19", Index: "smoke"}, wantErr: "creating index smoke: PUT /smoke: 400", wantIndex: true},
+		{name: "bulk fails", fault: &elasticsearchtest.Fault{Method: http.MethodPost, Path: "/smoke/_bulk", Status: http.StatusInternalServerError}, opts: Options{Version: "8.19", Index: "smoke"}, wantErr: "indexing into smoke: POST /smoke/_bulk?refresh=wait_for: 500"},
+		{name: "delete fails", fault: &elasticsearchtest.Fault{Method: http.MethodDelete, Path: "/smoke", Status: http.StatusInternalServerError}, opts: Options{Version: "8.19", Index: "smoke"}, wantErr: "deleting index smoke: DELETE /smoke: 500", wantIndex: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.version == "" {
+				tc.version = "8.19.3"
+			}
+			server := elasticsearchtest.NewServer(elasticsearchtest.Options{Version: tc.version, Users: map[string]string{"admin": "secret"}}) // pragma: allowlist secret
+			t.Cleanup(server.Close)
+			if tc.status != "" {
+				server.SetStatus(tc.status)
+			}
+			if tc.fault != nil {
+				server.Inject(*tc.fault)
+			}
+			client, err := elasticsearch.New(server.Credentials("admin"))
+			require.NoError(t, err)
+			if tc.index != "" {
+				require.NoError(t, client.Do(context.Background(), http.MethodPut, "/"+tc.index, nil, nil))
+			}
+
+			err = Verify(context.Background(), client, tc.opts)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			_, ok := server.Documents("smoke")
+			assert.Equal(t, tc.wantIndex, ok)
+		})
+	}
+}
+
+func TestFromOutputs(t *testing.T) {
+	object := `{"hostname": "private.host", "port": "30000", "certificate": %s, "credentials": {"admin": {"username": "u", "password": "p"}}}` // pragma: allowlist secret
+	classic := map[string]outputs.Value{
+		"hostname":                   {Value: json.RawMessage(`"public.host"`)},
+		"port":                       {Value: json.RawMessage(`31234`)},
+		"certificate_base64":         {Value: json.RawMessage(`"cHVibGlj"`)},
+		"service_credentials_object": {Value: json.RawMessage(strings.Replace(object, "%s", `"Y2VydA=="`, 1))},
+	}
+	c, err := FromOutputs(classic, "admin", false)
+	require.NoError(t, err)
+	assert.Equal(t, credentials.Credentials{Name: "admin", Username: "u", Password: "p", Hostname: "public.host", Port: 31234, CertificateBase64: "cHVibGlj"}, c) // pragma: allowlist secret
+
+	// terraform omits the null outputs
+	gen2 := map[string]outputs.Value{
+		"service_credentials_object": {Value: json.RawMessage(strings.Replace(object, "%s", `null`, 1))},
+	}
+	c, err = FromOutputs(gen2, "admin", true)
+	require.NoError(t, err)
+	assert.Equal(t, credentials.Credentials{Name: "admin", Username: "u", Password: "p", Hostname: "private.host", Port: 30000, Gen2: true}, c) // pragma: allowlist secret
+
+	// the complete example has no certificate_base64 output
+	delete(classic, "certificate_base64")
+	c, err = FromOutputs(classic, "admin", false)
+	require.NoError(t, err)
+	assert.Equal(t, "Y2VydA==", c.CertificateBase64)
+
+	gen2["port"] = outputs.Value{Value: json.RawMessage(`31234`)}
+	_, err = FromOutputs(gen2, "admin", true)
+	assert.ErrorContains(t, err, "output port is not null on gen2")
+	_, err = FromOutputs(gen2, "viewer", true)
+	assert.ErrorContains(t, err, "no credential viewer")
+	_, err = FromOutputs(map[string]outputs.Value{}, "admin", false)
+	assert.ErrorContains(t, err, "no service_credentials_object output")
+}
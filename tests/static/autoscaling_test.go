@@ -0,0 +1,113 @@
+package static
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/autoscaling"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// autoScalingAccepted reports whether the auto_scaling validation in variables.tf accepts the configuration
+func autoScalingAccepted(t *testing.T, m *tfconfig.Module, c autoscaling.Config) bool {
+	t.Helper()
+	failures, err := m.Validate(tfconfig.Inputs{"auto_scaling": c.HCL()})
+	require.NoError(t, err)
+	for _, failure := range failures {
+		require.Equal(t, fail("auto_scaling", msgAutoScaling), failure)
+	}
+	return len(failures) == 0
+}
+
+func checkAutoScalingAgreement(t *testing.T, m *tfconfig.Module, c autoscaling.Config) {
+	t.Helper()
+	modelErr := c.Validate()
+	accepted := autoScalingAccepted(t, m, c)
+	if accepted != (modelErr == nil) {
+		t.Errorf("variables.tf accepted=%t but the model returned %v for disk %v %s, memory %v %s",
+			accepted, modelErr, c.Disk.RateLimitMBPerMember, c.Disk.RateUnits, c.Memory.RateLimitMBPerMember, c.Memory.RateUnits)
+	}
+}
+
+// randomLimit returns a rate limit that is often on or next to one of the limits of the module, in random units
+func randomLimit(r *rand.Rand, resource string) (float64, string) {
+	units := autoscaling.Units[r.Intn(len(autoscaling.Units))]
+	if r.Intn(20) == 0 {
+		units = []string{"pb", "MB", "Gb", ""}[r.Intn(4)]
+	}
+	lowest, highest, err := autoscaling.Limits(resource, units)
+	if err != nil {
+		// unknown units, which are rejected whatever the value
+		lowest, highest, _ = autoscaling.Limits(resource, autoscaling.GB)
+	}
+
+	switch r.Intn(3) {
+	case 0:
+		bound := []float64{lowest, highest}[r.Intn(2)]
+		// on the bound, or one representable number either side of it
+		return []float64{bound, math.Nextafter(bound, 0), math.Nextafter(bound, math.Inf(1))}[r.Intn(3)], units
+	case 1:
+		// anywhere in or around the allowed range
+		return lowest/2 + r.Float64()*(highest*2-lowest/2), units
+	default:
+		// any order of magnitude
+		return math.Pow(10, r.Float64()*14-6), units
+	}
+}
+
+// The Go model and the HCL validation must agree for any input, so the bounds cannot drift apart
+func TestAutoScalingModelMatchesValidation(t *testing.T) {
+	m := loadRootModule(t)
+	// a fixed seed keeps the samples the same on every run, FuzzAutoScalingModelMatchesValidation explores further
+	r := rand.New(rand.NewSource(1))
+
+	samples := 1000
+	if testing.Short() {
+		samples = 200
+	}
+	for i := 0; i < samples; i++ {
+		c := autoscaling.Default()
+		c.Disk.RateLimitMBPerMember, c.Disk.RateUnits = randomLimit(r, "disk")
+		c.Memory.RateLimitMBPerMember, c.Memory.RateUnits = randomLimit(r, "memory")
+		checkAutoScalingAgreement(t, m, c)
+	}
+}
+
+func TestAutoScalingDefaultsMatchVariable(t *testing.T) {
+	m := loadRootModule(t)
+	values, err := m.Values(tfconfig.Inputs{"auto_scaling": `{disk = {}, memory = {}}`})
+	require.NoError(t, err)
+	defaults, err := m.Values(tfconfig.Inputs{"auto_scaling": autoscaling.Default().HCL()})
+	require.NoError(t, err)
+	require.True(t, values["auto_scaling"].Equals(defaults["auto_scaling"]).True(), "autoscaling.Default does not match the optional attribute defaults in variables.tf")
+}
+
+func FuzzAutoScalingModelMatchesValidation(f *testing.F) {
+	f.Add(5120.0, "mb", 4096.0, "mb")
+	f.Add(4096.0, "gb", 112.0, "gb")
+	f.Add(0.005, "tb", 0.109, "tb")
+	f.Add(5.0/1024, "tb", 112.0/1024, "tb")
+	f.Add(1.0, "pb", 1.0, "")
+
+	m, err := tfconfig.LoadModule(rootModuleDir)
+	require.NoError(f, err)
+	f.Fuzz(func(t *testing.T, disk float64, diskUnits string, memory float64, memoryUnits string) {
+		if math.IsNaN(disk) || math.IsInf(disk, 0) || math.IsNaN(memory) || math.IsInf(memory, 0) {
+			t.Skip("not representable in HCL")
+		}
+		for _, units := range []string{diskUnits, memoryUnits} {
+			if strconv.Quote(units) != `"`+units+`"` || strings.Contains(units, "${") || strings.Contains(units, "%{") {
+				t.Skip("units need escaping in HCL")
+			}
+		}
+		c := autoscaling.Default()
+		c.Disk.RateLimitMBPerMember, c.Disk.RateUnits = disk, diskUnits
+		c.Memory.RateLimitMBPerMember, c.Memory.RateUnits = memory, memoryUnits
+		checkAutoScalingAgreement(t, m, c)
+	})
+}
@@ -0,0 +1,29 @@
+package static
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+func TestCatalogMatchesSolutions(t *testing.T) {
+	manifest, err := catalog.Load(filepath.Join(rootModuleDir, "ibm_catalog.json"))
+	require.NoError(t, err)
+
+	for _, name := range []string{classicDA, gen2DA} {
+		t.Run(name, func(t *testing.T) {
+			flavor, err := manifest.Flavor(name)
+			require.NoError(t, err)
+			assert.Equal(t, "solutions/"+name, flavor.WorkingDirectory)
+
+			m, err := tfconfig.LoadModule(filepath.Join(rootModuleDir, flavor.WorkingDirectory))
+			require.NoError(t, err)
+			assert.NoError(t, flavor.Check(m))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package static
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/catalog"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+// stand-ins for the common permanent resources, which are not available offline
+var samplePermanentResources = map[string]any{
+	"hpcs_south_crn":            "crn:v1:bluemix:public:hs-crypto:us-south:a/abc:11111111-2222-3333-4444-555555555555::",
+	"kp_dedicated_us_south_crn": "crn:v1:bluemix:public:kms:us-south:a/abc:11111111-2222-3333-4444-555555555555::",
+}
+
+var sampleGeneratedValues = map[string]any{
+	"VALIDATION_APIKEY": "api-key",
+	"PREFIX":            "val",
+	"TAGS":              []string{"validation"},
+}
+
+func TestCatalogValidationValues(t *testing.T) {
+	manifest, err := catalog.Load(filepath.Join(rootModuleDir, "ibm_catalog.json"))
+	require.NoError(t, err)
+
+	for _, name := range []string{classicDA, gen2DA} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(rootModuleDir, "solutions", name)
+			template, err := os.ReadFile(filepath.Join(dir, catalog.ValidationValuesTemplate))
+			require.NoError(t, err)
+			values, err := catalog.Values(template, samplePermanentResources, sampleGeneratedValues)
+			require.NoError(t, err)
+			rendered, err := catalog.Render(template, values)
+			require.NoError(t, err)
+
+			flavor, err := manifest.Flavor(name)
+			require.NoError(t, err)
+			inputs, err := flavor.Inputs(rendered)
+			require.NoError(t, err)
+			m, err := tfconfig.LoadModule(dir)
+			require.NoError(t, err)
+			assert.NoError(t, catalog.CheckInputs(m, inputs))
+		})
+	}
+}
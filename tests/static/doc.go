@@ -0,0 +1,3 @@
+// Package static holds tests of the Terraform configuration in this repository that run without a provider,
+// credentials or cloud access. The Terraform files are read and evaluated with the tfconfig package.
+package static
@@ -0,0 +1,169 @@
+package static
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+const backupKeyCRN = `"crn:v1:bluemix:public:hs-crypto:us-south:a/abc:5678::key:9012"`
+
+// encryptionCase is one combination of the inputs that drive encryption. kms_key_crn is passed whenever the
+// IBM owned key is not used, as required by the validations.
+type encryptionCase struct {
+	ibmOwnedKey      bool
+	defaultBackupKey bool
+	sameKeyForBackup bool
+	backupKey        bool
+	gen2             bool
+}
+
+func (c encryptionCase) String() string {
+	return fmt.Sprintf("ibm_owned=%t/default_backup=%t/same_key=%t/backup_key=%t/gen2=%t",
+		c.ibmOwnedKey, c.defaultBackupKey, c.sameKeyForBackup, c.backupKey, c.gen2)
+}
+
+func (c encryptionCase) inputs() tfconfig.Inputs {
+	inputs := tfconfig.Inputs{}
+	if c.gen2 {
+		inputs = gen2Inputs(nil)
+	}
+	inputs["use_ibm_owned_encryption_key"] = fmt.Sprint(c.ibmOwnedKey)
+	inputs["use_default_backup_encryption_key"] = fmt.Sprint(c.defaultBackupKey)
+	inputs["use_same_kms_key_for_backups"] = fmt.Sprint(c.sameKeyForBackup)
+	if !c.ibmOwnedKey {
+		inputs["kms_key_crn"] = kmsKeyCRN
+	}
+	if c.backupKey {
+		inputs["backup_encryption_key_crn"] = backupKeyCRN
+	}
+	return inputs
+}
+
+// encryptionCases enumerates every combination of the encryption inputs
+func encryptionCases() []encryptionCase {
+	var cases []encryptionCase
+	for i := 0; i < 1<<5; i++ {
+		cases = append(cases, encryptionCase{
+			ibmOwnedKey:      i&1 != 0,
+			defaultBackupKey: i&2 != 0,
+			sameKeyForBackup: i&4 != 0,
+			backupKey:        i&8 != 0,
+			gen2:             i&16 != 0,
+		})
+	}
+	return cases
+}
+
+// encryptionOutcome is what a combination is expected to plan
+type encryptionOutcome struct {
+	valid bool
+	// dataKey and backupKey are the key CRNs passed to ibm_database, as HCL expressions
+	dataKey             string
+	backupKey           string
+	kmsPolicies         int
+	backupKMSPolicies   int
+	kmsPolicyWaits      int
+	backupKMSPolicyWait int
+}
+
+// expectedEncryption states the documented behaviour of the encryption inputs independently of main.tf
+func expectedEncryption(c encryptionCase) encryptionOutcome {
+	var valid bool
+	switch {
+	case c.ibmOwnedKey:
+		// the IBM owned key is used for everything, so no key may be passed
+		valid = !c.backupKey
+	case c.gen2:
+		// gen2 has no separate backup encryption
+		valid = !c.backupKey
+	case c.backupKey:
+		// a separate backup key is only used when neither the data key nor the default backup encryption is wanted
+		valid = !c.sameKeyForBackup && !c.defaultBackupKey
+	default:
+		// without a separate backup key, backups must use the data key
+		valid = c.sameKeyForBackup
+	}
+
+	outcome := encryptionOutcome{valid: valid, dataKey: "null", backupKey: "null"}
+	if c.ibmOwnedKey {
+		return outcome
+	}
+	outcome.dataKey = kmsKeyCRN
+	outcome.kmsPolicies, outcome.kmsPolicyWaits = 1, 1
+	if c.gen2 || c.defaultBackupKey {
+		return outcome
+	}
+	if c.backupKey {
+		outcome.backupKey = backupKeyCRN
+	} else {
+		outcome.backupKey = kmsKeyCRN
+	}
+	if !c.sameKeyForBackup {
+		outcome.backupKMSPolicies, outcome.backupKMSPolicyWait = 1, 1
+	}
+	return outcome
+}
+
+func TestEncryptionDecisionTable(t *testing.T) {
+	m := loadRootModule(t)
+
+	for _, c := range encryptionCases() {
+		t.Run(c.String(), func(t *testing.T) {
+			want := expectedEncryption(c)
+			inputs := c.inputs()
+
+			failures, err := m.Validate(inputs)
+			require.NoError(t, err)
+			if !want.valid {
+				assert.NotEmpty(t, failures, "combination is accepted by the validations")
+				return
+			}
+			require.Empty(t, failures, "combination is rejected by the validations")
+
+			dataKey, err := m.BlockAttribute(inputs, "key_protect_key", "resource", "ibm_database", "elasticsearch")
+			require.NoError(t, err)
+			assert.Equal(t, hclValue(t, want.dataKey), dataKey, "key_protect_key")
+
+			backupKey, err := m.BlockAttribute(inputs, "backup_encryption_key_crn", "resource", "ibm_database", "elasticsearch")
+			require.NoError(t, err)
+			assert.Equal(t, hclValue(t, want.backupKey), backupKey, "backup_encryption_key_crn")
+
+			for _, resource := range []struct {
+				resourceType, name string
+				want               int
+			}{
+				{"ibm_iam_authorization_policy", "kms_policy", want.kmsPolicies},
+				{"ibm_iam_authorization_policy", "backup_kms_policy", want.backupKMSPolicies},
+				{"time_sleep", "wait_for_authorization_policy", want.kmsPolicyWaits},
+				{"time_sleep", "wait_for_backup_kms_authorization_policy", want.backupKMSPolicyWait},
+			} {
+				count, err := m.Count(inputs, "resource", resource.resourceType, resource.name)
+				require.NoError(t, err)
+				assert.Equal(t, resource.want, count, "%s.%s", resource.resourceType, resource.name)
+			}
+
+			// a classic instance with its own key must never fall back to unencrypted backups unless asked to
+			if !c.gen2 && !c.ibmOwnedKey && !c.defaultBackupKey {
+				assert.False(t, backupKey.IsNull(), "backups are not encrypted with a customer key")
+			}
+		})
+	}
+}
+
+// hclValue evaluates a literal HCL expression such as "null" or a quoted string
+func hclValue(t *testing.T, expr string) cty.Value {
+	t.Helper()
+	if expr == "null" {
+		return cty.NullVal(cty.String)
+	}
+	var value string
+	_, err := fmt.Sscanf(expr, "%q", &value)
+	require.NoError(t, err)
+	return cty.StringVal(value)
+}
@@ -0,0 +1,371 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+const gen2DADir = "../../solutions/fully-configurable-gen2"
+
+// behaviour is how the module treats a root input on a platform
+type behaviour string
+
+const (
+	// accepted inputs pass validation and change what is planned
+	accepted behaviour = "accepted"
+	// ignored inputs pass validation but change nothing
+	ignored behaviour = "ignored"
+	// rejected inputs fail validation
+	rejected behaviour = "rejected"
+)
+
+// platformCase is an input on one platform. extra is applied to both the baseline and the sample,
+// so only sample differs between them.
+type platformCase struct {
+	extra  tfconfig.Inputs
+	sample tfconfig.Inputs
+	want   behaviour
+}
+
+type conformanceCase struct {
+	variable string
+	classic  platformCase
+	gen2     platformCase
+	// probe renders what the input affects in the plan, to tell accepted from ignored
+	probe func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string
+}
+
+func merge(all ...tfconfig.Inputs) tfconfig.Inputs {
+	merged := tfconfig.Inputs{}
+	for _, inputs := range all {
+		for name, value := range inputs {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+func databaseAttribute(name string) func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+	return func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+		value, err := m.BlockAttribute(inputs, name, "resource", "ibm_database", "elasticsearch")
+		require.NoError(t, err)
+		return value.GoString()
+	}
+}
+
+func databaseBlocks(blockType string) func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+	return func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+		blocks, err := m.NestedBlocks(inputs, blockType, "resource", "ibm_database", "elasticsearch")
+		require.NoError(t, err)
+		return renderBlocks(blocks)
+	}
+}
+
+func count(blockType string, labels ...string) func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+	return func(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+		n, err := m.Count(inputs, blockType, labels...)
+		require.NoError(t, err)
+		return fmt.Sprint(n)
+	}
+}
+
+func credentials(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+	var rendered []string
+	for _, attribute := range []string{"role", "parameters"} {
+		values, err := m.ForEachAttribute(inputs, attribute, "resource", "ibm_resource_key", "service_credentials")
+		require.NoError(t, err)
+		for key, value := range values {
+			rendered = append(rendered, fmt.Sprintf("%s.%s=%s", key, attribute, value.GoString()))
+		}
+	}
+	sort.Strings(rendered)
+	return strings.Join(rendered, "\n")
+}
+
+func backupEncryption(t *testing.T, m *tfconfig.Module, inputs tfconfig.Inputs) string {
+	return databaseAttribute("backup_encryption_key_crn")(t, m, inputs) + " " +
+		count("resource", "ibm_iam_authorization_policy", "backup_kms_policy")(t, m, inputs)
+}
+
+func renderBlocks(blocks []*tfconfig.NestedBlock) string {
+	var rendered []string
+	for _, block := range blocks {
+		var attributes []string
+		for name, value := range block.Attributes {
+			attributes = append(attributes, name+"="+value.GoString())
+		}
+		sort.Strings(attributes)
+		rendered = append(rendered, fmt.Sprintf("%s{%s%s}", block.Type, strings.Join(attributes, " "), renderBlocks(block.Blocks)))
+	}
+	return strings.Join(rendered, " ")
+}
+
+func input(name, value string) tfconfig.Inputs {
+	return tfconfig.Inputs{name: value}
+}
+
+var ownKey = tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN}
+
+// conformanceCases describes every root input on classic and gen2 plans
+var conformanceCases = []conformanceCase{
+	{variable: "resource_group_id", classic: platformCase{sample: input("resource_group_id", `"rg"`), want: accepted}, gen2: platformCase{sample: input("resource_group_id", `"rg"`), want: accepted}, probe: databaseAttribute("resource_group_id")},
+	{variable: "name", classic: platformCase{sample: input("name", `"es"`), want: accepted}, gen2: platformCase{sample: input("name", `"es"`), want: accepted}, probe: databaseAttribute("name")},
+	{variable: "elasticsearch_version", classic: platformCase{sample: input("elasticsearch_version", `"8.19"`), want: accepted}, gen2: platformCase{sample: input("elasticsearch_version", `"8.19"`), want: accepted}, probe: databaseAttribute("version")},
+	{variable: "region", classic: platformCase{sample: input("region", `"eu-de"`), want: accepted}, gen2: platformCase{sample: input("region", `"eu-fr2"`), want: accepted}, probe: databaseAttribute("location")},
+	{variable: "plan", classic: platformCase{sample: input("plan", `"platinum"`), want: accepted}, gen2: platformCase{sample: input("plan", `"platinum-gen2"`), want: rejected}, probe: databaseAttribute("plan")},
+	{variable: "members", classic: platformCase{sample: input("members", "5"), want: accepted}, gen2: platformCase{sample: input("members", "5"), want: accepted}, probe: databaseBlocks("group")},
+	// gen2 requires a dedicated host flavor, which sizes CPU and memory
+	{variable: "cpu_count", classic: platformCase{sample: input("cpu_count", "6"), want: accepted}, gen2: platformCase{sample: input("cpu_count", "6"), want: ignored}, probe: databaseBlocks("group")},
+	{variable: "memory_mb", classic: platformCase{sample: input("memory_mb", "8192"), want: accepted}, gen2: platformCase{sample: input("memory_mb", "8192"), want: ignored}, probe: databaseBlocks("group")},
+	{variable: "disk_mb", classic: platformCase{sample: input("disk_mb", "20480"), want: accepted}, gen2: platformCase{sample: input("disk_mb", "20480"), want: accepted}, probe: databaseBlocks("group")},
+	{variable: "member_host_flavor", classic: platformCase{sample: input("member_host_flavor", `"multitenant"`), want: accepted}, gen2: platformCase{sample: input("member_host_flavor", `"multitenant"`), want: rejected}, probe: databaseBlocks("group")},
+	{variable: "member_host_flavor", classic: platformCase{sample: input("member_host_flavor", `"b3c.4x16.encryption"`), want: accepted}, gen2: platformCase{sample: input("member_host_flavor", `"bx3d.8x40"`), want: accepted}, probe: databaseBlocks("group")},
+	{variable: "admin_pass", classic: platformCase{sample: input("admin_pass", `"a-long-password"`), want: accepted}, gen2: platformCase{sample: input("admin_pass", `"a-long-password"`), want: rejected}, probe: databaseAttribute("adminpassword")},
+	{
+		variable: "users",
+		classic:  platformCase{sample: input("users", `[{name = "user", password = "a-long-password"}]`), want: accepted},
+		gen2:     platformCase{sample: input("users", `[{name = "user", password = "a-long-password"}]`), want: rejected},
+		probe:    databaseBlocks("users"),
+	},
+	{
+		variable: "service_credential_names",
+		classic:  platformCase{sample: input("service_credential_names", `[{name = "a", role = "Editor", endpoint = "public"}]`), want: accepted},
+		gen2:     platformCase{sample: input("service_credential_names", `[{name = "a", role = "Editor"}]`), want: rejected},
+		probe:    credentials,
+	},
+	{
+		variable: "service_credential_names",
+		classic:  platformCase{sample: input("service_credential_names", `[{name = "a", role = "Writer", endpoint = "public"}]`), want: rejected},
+		gen2:     platformCase{sample: input("service_credential_names", `[{name = "a", role = "Writer"}]`), want: accepted},
+		probe:    credentials,
+	},
+	{
+		variable: "service_credential_names",
+		classic:  platformCase{sample: input("service_credential_names", `[{name = "a"}]`), want: rejected},
+		gen2:     platformCase{sample: input("service_credential_names", `[{name = "a", role = "Manager", endpoint = "public"}]`), want: rejected},
+	},
+	{variable: "service_endpoints", classic: platformCase{sample: input("service_endpoints", `"private"`), want: accepted}, gen2: platformCase{sample: input("service_endpoints", `"public"`), want: rejected}, probe: databaseAttribute("service_endpoints")},
+	{variable: "service_endpoints", classic: platformCase{sample: input("service_endpoints", `"public-and-private"`), want: accepted}, gen2: platformCase{sample: input("service_endpoints", `"public-and-private"`), want: rejected}, probe: databaseAttribute("service_endpoints")},
+	{variable: "resource_tags", classic: platformCase{sample: input("resource_tags", `["env:test"]`), want: accepted}, gen2: platformCase{sample: input("resource_tags", `["env:test"]`), want: accepted}, probe: databaseAttribute("tags")},
+	{variable: "access_tags", classic: platformCase{sample: input("access_tags", `["env:test"]`), want: accepted}, gen2: platformCase{sample: input("access_tags", `["env:test"]`), want: accepted}, probe: count("resource", "ibm_resource_tag", "elasticsearch_tag")},
+	{variable: "version_upgrade_skip_backup", classic: platformCase{sample: input("version_upgrade_skip_backup", "true"), want: accepted}, gen2: platformCase{sample: input("version_upgrade_skip_backup", "true"), want: accepted}, probe: databaseAttribute("version_upgrade_skip_backup")},
+	{variable: "deletion_protection", classic: platformCase{sample: input("deletion_protection", "false"), want: accepted}, gen2: platformCase{sample: input("deletion_protection", "false"), want: accepted}, probe: databaseAttribute("deletion_protection")},
+	{variable: "update_timeout", classic: platformCase{sample: input("update_timeout", `"3h"`), want: accepted}, gen2: platformCase{sample: input("update_timeout", `"3h"`), want: accepted}, probe: databaseBlocks("timeouts")},
+	{variable: "create_timeout", classic: platformCase{sample: input("create_timeout", `"3h"`), want: accepted}, gen2: platformCase{sample: input("create_timeout", `"3h"`), want: accepted}, probe: databaseBlocks("timeouts")},
+	{variable: "delete_timeout", classic: platformCase{sample: input("delete_timeout", `"3h"`), want: accepted}, gen2: platformCase{sample: input("delete_timeout", `"3h"`), want: accepted}, probe: databaseBlocks("timeouts")},
+	{variable: "auto_scaling", classic: platformCase{sample: input("auto_scaling", `{disk = {}, memory = {}}`), want: accepted}, gen2: platformCase{sample: input("auto_scaling", `{disk = {}, memory = {}}`), want: rejected}, probe: databaseBlocks("auto_scaling")},
+	{
+		variable: "use_ibm_owned_encryption_key",
+		classic:  platformCase{sample: ownKey, want: accepted},
+		gen2:     platformCase{sample: ownKey, want: accepted},
+		probe:    count("resource", "ibm_iam_authorization_policy", "kms_policy"),
+	},
+	{
+		variable: "kms_key_crn",
+		classic:  platformCase{extra: ownKey, sample: input("kms_key_crn", hpcsKeyCRN), want: accepted},
+		gen2:     platformCase{extra: ownKey, sample: input("kms_key_crn", hpcsKeyCRN), want: accepted},
+		probe:    databaseAttribute("key_protect_key"),
+	},
+	// gen2 has no separate backup encryption
+	{
+		variable: "use_default_backup_encryption_key",
+		classic:  platformCase{extra: ownKey, sample: input("use_default_backup_encryption_key", "true"), want: accepted},
+		gen2:     platformCase{extra: ownKey, sample: input("use_default_backup_encryption_key", "true"), want: ignored},
+		probe:    backupEncryption,
+	},
+	{
+		variable: "use_same_kms_key_for_backups",
+		classic:  platformCase{extra: ownKey, sample: input("use_same_kms_key_for_backups", "false"), want: rejected},
+		gen2:     platformCase{extra: ownKey, sample: input("use_same_kms_key_for_backups", "false"), want: ignored},
+		probe:    backupEncryption,
+	},
+	{
+		variable: "backup_encryption_key_crn",
+		classic:  platformCase{extra: ownKey, sample: tfconfig.Inputs{"use_same_kms_key_for_backups": "false", "backup_encryption_key_crn": hpcsKeyCRN}, want: accepted},
+		gen2:     platformCase{extra: ownKey, sample: tfconfig.Inputs{"use_same_kms_key_for_backups": "false", "backup_encryption_key_crn": hpcsKeyCRN}, want: rejected},
+		probe:    backupEncryption,
+	},
+	{
+		variable: "skip_iam_authorization_policy",
+		classic:  platformCase{extra: ownKey, sample: input("skip_iam_authorization_policy", "true"), want: accepted},
+		gen2:     platformCase{extra: ownKey, sample: input("skip_iam_authorization_policy", "true"), want: accepted},
+		probe:    count("resource", "ibm_iam_authorization_policy", "kms_policy"),
+	},
+	{variable: "cbr_rules", classic: platformCase{sample: input("cbr_rules", "["+cbrRule+"]"), want: accepted}, gen2: platformCase{sample: input("cbr_rules", "["+cbrRule+"]"), want: accepted}, probe: count("module", "cbr_rule")},
+	{variable: "backup_crn", classic: platformCase{sample: input("backup_crn", backupCRN), want: accepted}, gen2: platformCase{sample: input("backup_crn", backupCRN), want: rejected}, probe: databaseAttribute("backup_id")},
+	{
+		variable: "enable_elser_model",
+		classic:  platformCase{extra: tfconfig.Inputs{"plan": `"platinum"`, "admin_pass": `"a-long-password"`}, sample: input("enable_elser_model", "true"), want: accepted},
+		gen2:     platformCase{extra: input("service_credential_names", `[{name = "a", role = "Manager"}]`), sample: input("enable_elser_model", "true"), want: rejected},
+		probe:    count("resource", "terraform_data", "put_vectordb_model"),
+	},
+	// only used when the ELSER model is enabled
+	{variable: "elser_model_type", classic: platformCase{sample: input("elser_model_type", `".elser_model_1"`), want: accepted}, gen2: platformCase{sample: input("elser_model_type", `".elser_model_1"`), want: accepted}},
+	{variable: "install_required_binaries", classic: platformCase{sample: input("install_required_binaries", "false"), want: accepted}, gen2: platformCase{sample: input("install_required_binaries", "false"), want: accepted}},
+}
+
+func checkConformance(t *testing.T, m *tfconfig.Module, base tfconfig.Inputs, c conformanceCase, p platformCase) {
+	t.Helper()
+	baseline := merge(base, p.extra)
+	sample := merge(baseline, p.sample)
+
+	failures, err := m.Validate(baseline)
+	require.NoError(t, err)
+	require.Empty(t, failures, "the baseline inputs must be valid")
+
+	failures, err = m.Validate(sample)
+	require.NoError(t, err)
+	if p.want == rejected {
+		assert.NotEmpty(t, failures, "%s is accepted", c.variable)
+		return
+	}
+	require.Empty(t, failures, "%s is rejected", c.variable)
+
+	if c.probe == nil {
+		require.Equal(t, accepted, p.want, "an ignored input needs a probe")
+		return
+	}
+	before, after := c.probe(t, m, baseline), c.probe(t, m, sample)
+	if p.want == ignored {
+		assert.Equal(t, before, after, "%s changes the plan", c.variable)
+	} else {
+		assert.NotEqual(t, before, after, "%s does not change the plan", c.variable)
+	}
+}
+
+func TestGen2Conformance(t *testing.T) {
+	m := loadRootModule(t)
+	for _, c := range conformanceCases {
+		t.Run(fmt.Sprintf("%s/classic/%s", c.variable, c.classic.want), func(t *testing.T) {
+			checkConformance(t, m, tfconfig.Inputs{}, c, c.classic)
+		})
+		t.Run(fmt.Sprintf("%s/gen2/%s", c.variable, c.gen2.want), func(t *testing.T) {
+			checkConformance(t, m, gen2Inputs(nil), c, c.gen2)
+		})
+	}
+}
+
+// Every root input must be described, so a new input has to be classified for gen2
+func TestGen2ConformanceCoversEveryInput(t *testing.T) {
+	m := loadRootModule(t)
+	described := map[string]bool{}
+	for _, c := range conformanceCases {
+		require.Contains(t, m.Variables, c.variable)
+		described[c.variable] = true
+	}
+	for _, name := range m.VariableOrder {
+		assert.True(t, described[name], "no conformance case for %q", name)
+	}
+}
+
+func TestServiceCredentialRoles(t *testing.T) {
+	m := loadRootModule(t)
+	credential := `[{name = "a", role = "Writer"}]`
+
+	for _, tc := range []struct {
+		name     string
+		inputs   tfconfig.Inputs
+		role     cty.Value
+		roleCRN  cty.Value
+		endpoint cty.Value
+	}{
+		{
+			name:     "classic",
+			inputs:   tfconfig.Inputs{"service_endpoints": `"private"`, "service_credential_names": `[{name = "a", role = "Editor"}]`},
+			role:     cty.StringVal("Editor"),
+			roleCRN:  cty.NullVal(cty.String),
+			endpoint: cty.StringVal("private"),
+		},
+		{
+			name:     "gen2",
+			inputs:   gen2Inputs(tfconfig.Inputs{"service_credential_names": credential}),
+			role:     cty.NullVal(cty.String),
+			roleCRN:  cty.StringVal("crn:v1:bluemix:public:iam::::role:Writer"),
+			endpoint: cty.StringVal("private"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			roles, err := m.ForEachAttribute(tc.inputs, "role", "resource", "ibm_resource_key", "service_credentials")
+			require.NoError(t, err)
+			assert.Equal(t, tc.role, roles["a"])
+
+			parameters, err := m.ForEachAttribute(tc.inputs, "parameters", "resource", "ibm_resource_key", "service_credentials")
+			require.NoError(t, err)
+			assert.Equal(t, tc.roleCRN, parameters["a"].GetAttr("role_crn"))
+			assert.Equal(t, tc.endpoint, parameters["a"].GetAttr("service-endpoints"))
+		})
+	}
+}
+
+// The gen2 DA must not let a user set a value that the root module rejects for gen2
+func TestGen2DAInputsAcceptedByModule(t *testing.T) {
+	root := loadRootModule(t)
+	da, err := tfconfig.LoadModule(gen2DADir)
+	require.NoError(t, err)
+	daInputs := tfconfig.Inputs{"prefix": `"test"`, "ibmcloud_api_key": `"key"`}
+	// stand-ins for the arguments that come from other modules, as they are with the DA defaults
+	afterApply := map[string]cty.Value{
+		"resource_group_id": cty.StringVal("rg"),
+		"kms_key_crn":       cty.NullVal(cty.String), // kms_encryption_enabled is false
+	}
+
+	block, err := da.Block("module", "elasticsearch")
+	require.NoError(t, err)
+	arguments := map[string]cty.Value{}
+	passedThrough := map[string]string{}
+	for name, attr := range block.Body.Attributes {
+		if _, ok := root.Variables[name]; !ok {
+			continue // meta-argument
+		}
+		value, err := da.Evaluate(attr.Expr, daInputs)
+		if errors.Is(err, tfconfig.ErrKnownAfterApply) {
+			require.Contains(t, afterApply, name, "no stand-in for %s: %v", name, err)
+			value = afterApply[name]
+		} else {
+			require.NoError(t, err)
+		}
+		arguments[name] = value
+		if traversal, ok := attr.Expr.(*hclsyntax.ScopeTraversalExpr); ok && traversal.Traversal.RootName() == "var" && len(traversal.Traversal) == 2 {
+			passedThrough[name] = traversal.Traversal[1].(hcl.TraverseAttr).Name
+		}
+	}
+
+	// the DA defaults are valid for the module
+	values, err := root.ValuesFrom(arguments)
+	require.NoError(t, err)
+	failures, err := root.ValidateValues(values)
+	require.NoError(t, err)
+	assert.Empty(t, failures, "the gen2 DA defaults are rejected by the module")
+
+	for _, c := range conformanceCases {
+		if c.gen2.want != rejected {
+			continue
+		}
+		for name, value := range c.gen2.sample {
+			daVariable, ok := passedThrough[name]
+			if !ok {
+				// not exposed directly, so it must not depend on DA inputs at all
+				if attr, set := block.Body.Attributes[name]; set {
+					for _, traversal := range attr.Expr.Variables() {
+						assert.NotEqual(t, "var", traversal.RootName(), "the gen2 DA derives %s, which the module restricts on gen2, from its inputs", name)
+					}
+				}
+				continue
+			}
+			failures, err := da.Validate(merge(daInputs, input(daVariable, value)))
+			require.NoError(t, err)
+			assert.NotEmpty(t, failures, "the gen2 DA accepts %s = %s, which the module rejects for gen2", daVariable, value)
+		}
+	}
+}
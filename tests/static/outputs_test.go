@@ -0,0 +1,57 @@
+package static
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/outputs"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+func TestOutputContractsDeclared(t *testing.T) {
+	for _, contract := range outputs.Contracts {
+		t.Run(contract.Dir, func(t *testing.T) {
+			m, err := tfconfig.LoadModule(filepath.Join(rootModuleDir, contract.Dir))
+			require.NoError(t, err)
+			assert.NoError(t, contract.CheckDeclared(m))
+		})
+	}
+}
+
+// Every module with outputs needs a contract
+func TestOutputContractsCoverModules(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(rootModuleDir, "*", "*", "outputs.tf"))
+	require.NoError(t, err)
+	dirs = append(dirs, filepath.Join(rootModuleDir, "outputs.tf"))
+	for _, path := range dirs {
+		dir, err := filepath.Rel(rootModuleDir, filepath.Dir(path))
+		require.NoError(t, err)
+		_, err = outputs.For(filepath.ToSlash(dir))
+		assert.NoError(t, err)
+	}
+}
+
+// The root module outputs that the contract marks null on gen2 must evaluate to null for gen2 inputs, and the
+// others must not
+func TestRootOutputsNullOnGen2(t *testing.T) {
+	m := loadRootModule(t)
+	contract, err := outputs.For(".")
+	require.NoError(t, err)
+
+	knownNull := func(value cty.Value) bool {
+		return value.IsKnown() && value.IsNull()
+	}
+	for _, output := range contract.Outputs {
+		gen2, err := m.Output(gen2Inputs(nil), output.Name)
+		require.NoError(t, err)
+		classic, err := m.Output(tfconfig.Inputs{}, output.Name)
+		require.NoError(t, err)
+
+		assert.Equal(t, output.NullOnGen2, knownNull(gen2), "%s on gen2 is %#v", output.Name, gen2)
+		assert.False(t, knownNull(classic), "%s is always null on classic", output.Name)
+	}
+}
@@ -0,0 +1,63 @@
+package static
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/parity"
+)
+
+const (
+	classicDA = "fully-configurable"
+	gen2DA    = "fully-configurable-gen2"
+)
+
+type parityKey struct {
+	kind     parity.Kind
+	solution string
+	variable string
+}
+
+// expectedParityFindings are the intended differences, with the reason for each
+var expectedParityFindings = map[parityKey]string{
+	{parity.DefaultMismatch, classicDA, "plan"}:               "the DA defaults to platinum, which supports every version and ELSER",
+	{parity.DefaultMismatch, classicDA, "cpu_count"}:          "the DA allocates dedicated CPU by default",
+	{parity.DefaultMismatch, classicDA, "member_host_flavor"}: "the DA defaults to a dedicated host flavor",
+	{parity.DefaultMismatch, classicDA, "service_endpoints"}:  "the DA is private by default",
+	{parity.DefaultMismatch, classicDA, "auto_scaling"}:       "the DA exposes the provider defaults explicitly",
+
+	{parity.DefaultMismatch, gen2DA, "region"}:             "gen2 is not available in the root module default region",
+	{parity.DefaultMismatch, gen2DA, "disk_mb"}:            "gen2 needs at least 10240 MB of disk",
+	{parity.DefaultMismatch, gen2DA, "member_host_flavor"}: "gen2 needs a dedicated host flavor",
+	{parity.Unwired, gen2DA, "cbr_rules"}:                  "the gen2 DA does not support context-based restrictions yet",
+	{parity.Unwired, gen2DA, "enable_elser_model"}:         "ELSER needs the admin user, which gen2 does not have",
+	{parity.Unwired, gen2DA, "elser_model_type"}:           "only used with ELSER",
+	{parity.Unwired, gen2DA, "install_required_binaries"}:  "the binaries are only needed for ELSER",
+
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "region"}:                             "gen2 links to the regions it is available in",
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "kms_encryption_enabled"}:             "gen2 has no backup key",
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "existing_kms_instance_crn"}:          "gen2 has no backup key and only supports Key Protect",
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "existing_kms_key_crn"}:               "gen2 has no backup key and only supports Key Protect",
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "kms_endpoint_type"}:                  "gen2 only supports Key Protect",
+	{parity.DescriptionMismatch, classicDA + "," + gen2DA, "skip_elasticsearch_kms_auth_policy"}: "gen2 only supports Key Protect",
+}
+
+func TestSolutionParity(t *testing.T) {
+	root := loadRootModule(t)
+	solutions, err := parity.Load(rootModuleDir, classicDA, gen2DA)
+	require.NoError(t, err)
+	findings, err := parity.Check(root, solutions)
+	require.NoError(t, err)
+
+	found := map[parityKey]bool{}
+	for _, finding := range findings {
+		key := parityKey{finding.Kind, finding.Solution, finding.Variable}
+		found[key] = true
+		assert.Contains(t, expectedParityFindings, key, "unexpected difference: %s", finding)
+	}
+	for key, reason := range expectedParityFindings {
+		assert.True(t, found[key], "%s %s of %s is no longer found (%s), remove it from expectedParityFindings", key.kind, key.variable, key.solution, reason)
+	}
+}
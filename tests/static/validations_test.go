@@ -0,0 +1,301 @@
+package static
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/tfconfig"
+)
+
+const rootModuleDir = "../.."
+
+// Error messages of the root module variable validations
+const (
+	msgPlan                    = "Only supported plans are enterprise, platinum and enterprise-gen2."
+	msgGen2Disk                = "`disk_mb` for Gen2 must be 10240 or more, either set the `disk_mb` input or select a classic `plan`."
+	msgClassicDisk             = "`disk_mb` for Classic must be 5120 or more, set the `disk_mb`."
+	msgGen2HostFlavor          = "`member_host_flavor` is required for Gen2 instances and cannot be `multitenant`, set a host flavor (for example `bx3d.4x20`) or select a classic `plan`."
+	msgGen2AdminPass           = "`admin_pass` is only supported for classic instances, remove `admin_pass` or select a classic `plan`."
+	msgGen2Users               = "`users` is only supported for classic instances, remove `users` or select a classic `plan`."
+	msgGen2CredentialRole      = "`service_credential_names` role must be one of the following: `Manager` or `Writer` for Gen2 instances."
+	msgClassicCredentialRole   = "`service_credential_names` role must be one of the following: `Administrator`, `Operator`, `Viewer` or `Editor` for classic instances."
+	msgCredentialEndpoint      = "`service_credential_names` endpoint must be `public` or `private`."
+	msgPrivateCredentialPublic = "When `service_endpoints` is set to `private`, `service_credential_names.endpoint` value cannot be `public`."
+	msgPublicCredentialPrivate = "When `service_endpoints` is set to `public`, `service_credential_names.endpoint` value cannot be `private`."
+	msgServiceEndpoints        = "Valid values for service_endpoints are 'public', 'public-and-private', and 'private'"
+	msgGen2ServiceEndpoints    = "`service_endpoints` must be `private` for Gen2 instances."
+	msgResourceTags            = "Each resource tag must be 128 characters or less and may contain only A-Z, a-z, 0-9, spaces, underscore (_), hyphen (-), period (.), and colon (:)."
+	msgAccessTags              = "Tags must match the regular expression \"[\\w\\-_\\.]+:[\\w\\-_\\.]+\", see https://cloud.ibm.com/docs/account?topic=account-tag&interface=ui#limits for more details"
+	msgGen2AutoScaling         = "`auto_scaling` is only supported for classic instances, remove `auto_scaling` or select a classic `plan`."
+	msgIBMOwnedKeyWithCRN      = "When 'use_ibm_owned_encryption_key' is true, 'kms_key_crn' and 'backup_encryption_key_crn' must both be null."
+	msgMissingKMSKey           = "When setting 'use_ibm_owned_encryption_key' to false, a value must be passed for 'kms_key_crn'."
+	msgBackupKeyConflict       = "When passing a value for backup_encryption_key_crn, you should set use_same_kms_key_for_backups to false, use_default_backup_encryption_key to false and use_ibm_owned_encryption_key to false."
+	msgMissingBackupKey        = "When 'use_same_kms_key_for_backups' is set to false, a value needs to be passed for 'backup_encryption_key_crn'."
+	msgKMSKeyCRN               = "Value must be the KMS key CRN from a Key Protect or Hyper Protect Crypto Services instance."
+	msgBackupKeyCRN            = "Value must be the KMS key CRN from a Key Protect or Hyper Protect Crypto Services instance in one of the supported backup regions."
+	msgGen2BackupKey           = "`backup_encryption_key_crn` is only supported for classic instances, remove `backup_encryption_key_crn` or select a classic `plan`."
+	msgCBRRules                = "Only one CBR rule is allowed."
+	msgBackupCRN               = "backup_crn must be null OR start with 'crn:' and contain ':backup:'"
+	msgGen2BackupCRN           = "`backup_crn` is only supported for classic instances, remove `backup_crn` or select a classic `plan`."
+	msgElserPlan               = "When 'enable_elser_model' is set to true, the 'plan' must be set to 'platinum' in order to enable ELSER model."
+	msgElserAdministrator      = "When 'enable_elser_model' is set to true, an Administrator role user must be created using the 'service_credential_names' input, or by passing a value for the 'admin_pass' input."
+	msgElserModelType          = "The specified elser_model_type is not a valid selection!"
+	msgAutoScaling             = "For disk: rate_limit_mb_per_member must be between 5 and 4096 GB (5120-4194304 MB, 5-4096 GB, or 0.005-4 TB). For memory: rate_limit_mb_per_member must be between 4 and 112 GB (4096-114688 MB, 4-112 GB, or 0.004-0.109 TB). The rate_units must be one of: mb, gb, tb."
+)
+
+const (
+	kmsKeyCRN    = `"crn:v1:bluemix:public:kms:us-south:a/abc:1234::key:5678"`
+	hpcsKeyCRN   = `"crn:v1:bluemix:public:hs-crypto:us-south:a/abc:1234::key:5678"`
+	backupCRN    = `"crn:v1:bluemix:public:databases-for-elasticsearch:us-south:a/abc:1234:backup:5678"`
+	cbrRule      = `{description = "rule", account_id = "abc", rule_contexts = [], enforcement_mode = "report"}`
+	gen2Flavor   = `"bx3d.4x20"`
+	gen2DiskMB   = "10240"
+	gen2Plan     = `"enterprise-gen2"`
+	gen2Endpoint = `"private"`
+)
+
+func loadRootModule(t *testing.T) *tfconfig.Module {
+	t.Helper()
+	m, err := tfconfig.LoadModule(rootModuleDir)
+	require.NoError(t, err)
+	return m
+}
+
+// gen2Inputs returns the smallest valid set of Gen2 inputs, overridden by extra
+func gen2Inputs(extra tfconfig.Inputs) tfconfig.Inputs {
+	inputs := tfconfig.Inputs{
+		"plan":               gen2Plan,
+		"disk_mb":            gen2DiskMB,
+		"member_host_flavor": gen2Flavor,
+		"service_endpoints":  gen2Endpoint,
+	}
+	for name, value := range extra {
+		inputs[name] = value
+	}
+	return inputs
+}
+
+type validationCase struct {
+	name   string
+	inputs tfconfig.Inputs
+	want   []tfconfig.Failure
+}
+
+func fail(variable, message string) tfconfig.Failure {
+	return tfconfig.Failure{Variable: variable, ErrorMessage: message}
+}
+
+var validationCases = []validationCase{
+	{name: "classic defaults", inputs: tfconfig.Inputs{}},
+	{name: "platinum", inputs: tfconfig.Inputs{"plan": `"platinum"`}},
+	{name: "gen2 minimum", inputs: gen2Inputs(nil)},
+	{name: "unsupported plan", inputs: tfconfig.Inputs{"plan": `"standard"`}, want: []tfconfig.Failure{fail("plan", msgPlan)}},
+	{name: "unsupported gen2 plan", inputs: gen2Inputs(tfconfig.Inputs{"plan": `"platinum-gen2"`}), want: []tfconfig.Failure{fail("plan", msgPlan)}},
+
+	// disk_mb
+	{name: "classic disk at minimum", inputs: tfconfig.Inputs{"disk_mb": "5120"}},
+	{name: "classic disk below minimum", inputs: tfconfig.Inputs{"disk_mb": "5119"}, want: []tfconfig.Failure{fail("disk_mb", msgClassicDisk)}},
+	{name: "gen2 default disk", inputs: gen2Inputs(tfconfig.Inputs{"disk_mb": "5120"}), want: []tfconfig.Failure{fail("disk_mb", msgGen2Disk)}},
+	{name: "gen2 disk below minimum", inputs: gen2Inputs(tfconfig.Inputs{"disk_mb": "10239"}), want: []tfconfig.Failure{fail("disk_mb", msgGen2Disk)}},
+
+	// member_host_flavor
+	{name: "classic multitenant", inputs: tfconfig.Inputs{"member_host_flavor": `"multitenant"`}},
+	{name: "gen2 without host flavor", inputs: gen2Inputs(tfconfig.Inputs{"member_host_flavor": "null"}), want: []tfconfig.Failure{fail("member_host_flavor", msgGen2HostFlavor)}},
+	{name: "gen2 multitenant", inputs: gen2Inputs(tfconfig.Inputs{"member_host_flavor": `"multitenant"`}), want: []tfconfig.Failure{fail("member_host_flavor", msgGen2HostFlavor)}},
+
+	// admin_pass and users
+	{name: "classic admin_pass", inputs: tfconfig.Inputs{"admin_pass": `"a-long-password"`}},
+	{name: "gen2 admin_pass", inputs: gen2Inputs(tfconfig.Inputs{"admin_pass": `"a-long-password"`}), want: []tfconfig.Failure{fail("admin_pass", msgGen2AdminPass)}},
+	{name: "classic users", inputs: tfconfig.Inputs{"users": `[{name = "user", password = "a-long-password"}]`}},
+	{name: "gen2 users", inputs: gen2Inputs(tfconfig.Inputs{"users": `[{name = "user", password = "a-long-password"}]`}), want: []tfconfig.Failure{fail("users", msgGen2Users)}},
+
+	// service_credential_names
+	{name: "classic credential defaults", inputs: tfconfig.Inputs{"service_endpoints": `"private"`, "service_credential_names": `[{name = "a"}]`}},
+	{name: "classic credential with default private endpoint on public instance", inputs: tfconfig.Inputs{"service_credential_names": `[{name = "a"}]`}, want: []tfconfig.Failure{fail("service_credential_names", msgPublicCredentialPrivate)}},
+	{name: "classic credential roles", inputs: tfconfig.Inputs{"service_endpoints": `"public-and-private"`, "service_credential_names": `[{name = "a", role = "Administrator"}, {name = "b", role = "Operator", endpoint = "public"}, {name = "c", role = "Editor"}]`}},
+	{name: "classic Manager role", inputs: tfconfig.Inputs{"service_endpoints": `"private"`, "service_credential_names": `[{name = "a", role = "Manager"}]`}, want: []tfconfig.Failure{fail("service_credential_names", msgClassicCredentialRole)}},
+	{name: "gen2 credential roles", inputs: gen2Inputs(tfconfig.Inputs{"service_credential_names": `[{name = "a", role = "Manager"}, {name = "b", role = "Writer"}]`})},
+	{name: "gen2 default Viewer role", inputs: gen2Inputs(tfconfig.Inputs{"service_credential_names": `[{name = "a"}]`}), want: []tfconfig.Failure{fail("service_credential_names", msgGen2CredentialRole)}},
+	{
+		name:   "invalid endpoint",
+		inputs: tfconfig.Inputs{"service_endpoints": `"public-and-private"`, "service_credential_names": `[{name = "a", endpoint = "direct"}]`},
+		want:   []tfconfig.Failure{fail("service_credential_names", msgCredentialEndpoint)},
+	},
+	{
+		name:   "public credential on private instance",
+		inputs: tfconfig.Inputs{"service_endpoints": `"private"`, "service_credential_names": `[{name = "a", endpoint = "public"}]`},
+		want:   []tfconfig.Failure{fail("service_credential_names", msgPrivateCredentialPublic)},
+	},
+	{
+		name:   "gen2 public credential",
+		inputs: gen2Inputs(tfconfig.Inputs{"service_credential_names": `[{name = "a", role = "Writer", endpoint = "public"}]`}),
+		want:   []tfconfig.Failure{fail("service_credential_names", msgPrivateCredentialPublic)},
+	},
+
+	// service_endpoints
+	{name: "public-and-private endpoints", inputs: tfconfig.Inputs{"service_endpoints": `"public-and-private"`}},
+	{name: "invalid endpoints", inputs: tfconfig.Inputs{"service_endpoints": `"both"`}, want: []tfconfig.Failure{fail("service_endpoints", msgServiceEndpoints)}},
+	{name: "endpoints regex is anchored", inputs: tfconfig.Inputs{"service_endpoints": `"private-only"`}, want: []tfconfig.Failure{fail("service_endpoints", msgServiceEndpoints)}},
+	{name: "gen2 public endpoints", inputs: gen2Inputs(tfconfig.Inputs{"service_endpoints": `"public"`}), want: []tfconfig.Failure{fail("service_endpoints", msgGen2ServiceEndpoints)}},
+	{
+		name:   "gen2 invalid endpoints",
+		inputs: gen2Inputs(tfconfig.Inputs{"service_endpoints": `"both"`}),
+		want:   []tfconfig.Failure{fail("service_endpoints", msgServiceEndpoints), fail("service_endpoints", msgGen2ServiceEndpoints)},
+	},
+
+	// tags
+	{name: "valid tags", inputs: tfconfig.Inputs{"resource_tags": `["env:dev", "team a", "x_y-z.1"]`, "access_tags": `["env:dev", "project:a.b"]`}},
+	{name: "resource tag with invalid character", inputs: tfconfig.Inputs{"resource_tags": `["env=dev"]`}, want: []tfconfig.Failure{fail("resource_tags", msgResourceTags)}},
+	{name: "resource tag too long", inputs: tfconfig.Inputs{"resource_tags": `["` + strings.Repeat("a", 129) + `"]`}, want: []tfconfig.Failure{fail("resource_tags", msgResourceTags)}},
+	{name: "empty resource tag", inputs: tfconfig.Inputs{"resource_tags": `[""]`}, want: []tfconfig.Failure{fail("resource_tags", msgResourceTags)}},
+	{name: "access tag without value", inputs: tfconfig.Inputs{"access_tags": `["env"]`}, want: []tfconfig.Failure{fail("access_tags", msgAccessTags)}},
+	{name: "access tag too long", inputs: tfconfig.Inputs{"access_tags": `["env:` + strings.Repeat("a", 125) + `"]`}, want: []tfconfig.Failure{fail("access_tags", msgAccessTags)}},
+
+	// auto_scaling
+	{name: "auto_scaling defaults", inputs: tfconfig.Inputs{"auto_scaling": `{disk = {}, memory = {}}`}},
+	{name: "disk limit in mb at bounds", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("mb", "5120")}},
+	{name: "disk limit in mb at maximum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("mb", "4194304")}},
+	{name: "disk limit in mb below minimum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("mb", "5119")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "disk limit in mb above maximum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("mb", "4194305")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "disk limit in gb", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("gb", "4096")}},
+	{name: "disk limit in gb below minimum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("gb", "4")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "disk limit in tb", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("tb", "0.005")}},
+	{name: "disk limit in tb below rounded minimum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("tb", "0.0048828125")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "disk limit in tb above maximum", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("tb", "4.001")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "default disk limit in gb", inputs: tfconfig.Inputs{"auto_scaling": `{disk = {rate_units = "gb"}, memory = {}}`}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "memory limit in mb below minimum", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("mb", "4095")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "memory limit in gb", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("gb", "112")}},
+	{name: "memory limit in gb above maximum", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("gb", "113")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "memory limit in tb", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("tb", "0.004")}},
+	{name: "memory limit in tb above rounded maximum", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("tb", "0.109375")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "memory limit in tb above maximum", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("tb", "0.11")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "unknown disk rate units", inputs: tfconfig.Inputs{"auto_scaling": diskAutoScaling("pb", "1")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "unknown memory rate units", inputs: tfconfig.Inputs{"auto_scaling": memoryAutoScaling("MB", "4096")}, want: []tfconfig.Failure{fail("auto_scaling", msgAutoScaling)}},
+	{name: "gen2 auto_scaling", inputs: gen2Inputs(tfconfig.Inputs{"auto_scaling": `{disk = {}, memory = {}}`}), want: []tfconfig.Failure{fail("auto_scaling", msgGen2AutoScaling)}},
+
+	// encryption
+	{name: "own key", inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN}},
+	{name: "own HPCS key", inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": hpcsKeyCRN}},
+	{name: "IBM owned key with kms_key_crn", inputs: tfconfig.Inputs{"kms_key_crn": kmsKeyCRN}, want: []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgIBMOwnedKeyWithCRN)}},
+	{
+		name:   "IBM owned key with backup key",
+		inputs: tfconfig.Inputs{"backup_encryption_key_crn": kmsKeyCRN},
+		want:   []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgIBMOwnedKeyWithCRN)},
+	},
+	{name: "own key without kms_key_crn", inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false"}, want: []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgMissingKMSKey)}},
+	{
+		name:   "separate backup key",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false", "backup_encryption_key_crn": hpcsKeyCRN},
+	},
+	{
+		name:   "backup key with use_same_kms_key_for_backups",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "backup_encryption_key_crn": hpcsKeyCRN},
+		want:   []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgBackupKeyConflict)},
+	},
+	{
+		name:   "backup key with default backup encryption",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false", "use_default_backup_encryption_key": "true", "backup_encryption_key_crn": hpcsKeyCRN},
+		want:   []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgBackupKeyConflict)},
+	},
+	{
+		name:   "different backup key without a value",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false"},
+		want:   []tfconfig.Failure{fail("use_ibm_owned_encryption_key", msgMissingBackupKey)},
+	},
+	{
+		name:   "gen2 own key without separate backup key",
+		inputs: gen2Inputs(tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false"}),
+	},
+	{
+		name:   "kms_key_crn from another service",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": `"crn:v1:bluemix:public:secrets-manager:us-south:a/abc:1234::"`},
+		want:   []tfconfig.Failure{fail("kms_key_crn", msgKMSKeyCRN)},
+	},
+	{
+		name:   "backup key from another service",
+		inputs: tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false", "backup_encryption_key_crn": `"crn:v1:bluemix:public:secrets-manager:us-south:a/abc:1234::"`},
+		want:   []tfconfig.Failure{fail("backup_encryption_key_crn", msgBackupKeyCRN)},
+	},
+	{
+		name:   "gen2 backup key",
+		inputs: gen2Inputs(tfconfig.Inputs{"use_ibm_owned_encryption_key": "false", "kms_key_crn": kmsKeyCRN, "use_same_kms_key_for_backups": "false", "backup_encryption_key_crn": hpcsKeyCRN}),
+		want:   []tfconfig.Failure{fail("backup_encryption_key_crn", msgGen2BackupKey)},
+	},
+
+	// cbr_rules
+	{name: "one CBR rule", inputs: tfconfig.Inputs{"cbr_rules": "[" + cbrRule + "]"}},
+	{name: "two CBR rules", inputs: tfconfig.Inputs{"cbr_rules": "[" + cbrRule + ", " + cbrRule + "]"}, want: []tfconfig.Failure{fail("cbr_rules", msgCBRRules)}},
+
+	// backup_crn
+	{name: "restore from backup", inputs: tfconfig.Inputs{"backup_crn": backupCRN}},
+	{name: "backup_crn without backup segment", inputs: tfconfig.Inputs{"backup_crn": `"crn:v1:bluemix:public:databases-for-elasticsearch:us-south:a/abc:1234::"`}, want: []tfconfig.Failure{fail("backup_crn", msgBackupCRN)}},
+	{name: "backup_crn not a CRN", inputs: tfconfig.Inputs{"backup_crn": `"backup:1234:backup:"`}, want: []tfconfig.Failure{fail("backup_crn", msgBackupCRN)}},
+	{name: "gen2 restore from backup", inputs: gen2Inputs(tfconfig.Inputs{"backup_crn": backupCRN}), want: []tfconfig.Failure{fail("backup_crn", msgGen2BackupCRN)}},
+
+	// ELSER
+	{name: "ELSER with admin_pass", inputs: tfconfig.Inputs{"plan": `"platinum"`, "enable_elser_model": "true", "admin_pass": `"a-long-password"`}},
+	{
+		name:   "ELSER with Administrator credential",
+		inputs: tfconfig.Inputs{"plan": `"platinum"`, "enable_elser_model": "true", "service_credential_names": `[{name = "admin", role = "Administrator", endpoint = "public"}]`},
+	},
+	{
+		name:   "ELSER on enterprise",
+		inputs: tfconfig.Inputs{"enable_elser_model": "true", "admin_pass": `"a-long-password"`},
+		want:   []tfconfig.Failure{fail("enable_elser_model", msgElserPlan)},
+	},
+	{
+		name:   "ELSER without Administrator",
+		inputs: tfconfig.Inputs{"plan": `"platinum"`, "enable_elser_model": "true", "service_credential_names": `[{name = "viewer", endpoint = "public"}]`},
+		want:   []tfconfig.Failure{fail("enable_elser_model", msgElserAdministrator)},
+	},
+	{
+		name:   "ELSER on gen2",
+		inputs: gen2Inputs(tfconfig.Inputs{"enable_elser_model": "true", "service_credential_names": `[{name = "a", role = "Manager"}]`}),
+		want:   []tfconfig.Failure{fail("enable_elser_model", msgElserPlan), fail("enable_elser_model", msgElserAdministrator)},
+	},
+	{name: "ELSER model 1", inputs: tfconfig.Inputs{"elser_model_type": `".elser_model_1"`}},
+	{name: "unknown ELSER model", inputs: tfconfig.Inputs{"elser_model_type": `".elser_model_3"`}, want: []tfconfig.Failure{fail("elser_model_type", msgElserModelType)}},
+}
+
+func TestRootVariableValidations(t *testing.T) {
+	m := loadRootModule(t)
+	for _, tc := range validationCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failures, err := m.Validate(tc.inputs)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, failures)
+		})
+	}
+}
+
+// Every validation block in variables.tf must be exercised by a failing case above
+func TestRootVariableValidationsCovered(t *testing.T) {
+	m := loadRootModule(t)
+
+	covered := map[tfconfig.Failure]bool{}
+	for _, tc := range validationCases {
+		for _, failure := range tc.want {
+			covered[failure] = true
+		}
+	}
+
+	for _, name := range m.VariableOrder {
+		for _, validation := range m.Variables[name].Validations {
+			message, ok := tfconfig.StringLiteral(validation.ErrorMessage)
+			require.True(t, ok, "%s: error_message of %q is not a string literal", validation.DeclRange, name)
+			assert.True(t, covered[fail(name, message)], "%s: no test case fails the validation of %q: %s", validation.DeclRange, name, message)
+		}
+	}
+}
+
+func diskAutoScaling(units, limit string) string {
+	return fmt.Sprintf(`{disk = {rate_units = %q, rate_limit_mb_per_member = %s}, memory = {}}`, units, limit)
+}
+
+func memoryAutoScaling(units, limit string) string {
+	return fmt.Sprintf(`{disk = {}, memory = {rate_units = %q, rate_limit_mb_per_member = %s}}`, units, limit)
+}
@@ -0,0 +1,129 @@
+package tfconfig
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// NestedBlock is a nested block of a resource as Terraform sends it to the provider: dynamic blocks are expanded
+// and attributes are evaluated.
+type NestedBlock struct {
+	Type       string
+	Attributes map[string]cty.Value
+	Blocks     []*NestedBlock
+}
+
+// NestedBlocks returns the nested blocks of a type in a top level block for the inputs, for example
+// NestedBlocks(inputs, "group", "resource", "ibm_database", "elasticsearch"). Static blocks and expanded
+// `dynamic` blocks are returned in the order they are declared.
+func (m *Module) NestedBlocks(inputs Inputs, nestedType string, blockType string, labels ...string) ([]*NestedBlock, error) {
+	block, err := m.Block(blockType, labels...)
+	if err != nil {
+		return nil, err
+	}
+	values, err := m.Values(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return expandBlocks(m.evalContext(values, m.Locals(values)), block.Body, nestedType)
+}
+
+func expandBlocks(ctx *hcl.EvalContext, body *hclsyntax.Body, blockType string) ([]*NestedBlock, error) {
+	var expanded []*NestedBlock
+	for _, child := range body.Blocks {
+		switch {
+		case child.Type == blockType:
+			block, err := evalNestedBlock(ctx, blockType, child.Body)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, block)
+		case child.Type == "dynamic" && len(child.Labels) == 1 && child.Labels[0] == blockType:
+			blocks, err := expandDynamic(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, blocks...)
+		}
+	}
+	return expanded, nil
+}
+
+func expandDynamic(ctx *hcl.EvalContext, dynamic *hclsyntax.Block) ([]*NestedBlock, error) {
+	blockType := dynamic.Labels[0]
+	forEach, ok := dynamic.Body.Attributes["for_each"]
+	if !ok {
+		return nil, fmt.Errorf("%s: dynamic %q has no for_each", dynamic.DefRange(), blockType)
+	}
+	iterator := blockType
+	if attr, ok := dynamic.Body.Attributes["iterator"]; ok {
+		iterator = hcl.ExprAsKeyword(attr.Expr)
+	}
+	var content *hclsyntax.Block
+	for _, child := range dynamic.Body.Blocks {
+		if child.Type == "content" {
+			if content != nil {
+				return nil, fmt.Errorf("%s: dynamic %q has more than one content block", dynamic.DefRange(), blockType)
+			}
+			content = child
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%s: dynamic %q has no content block", dynamic.DefRange(), blockType)
+	}
+
+	collection, diags := forEach.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s: for_each of dynamic %q: %w", dynamic.DefRange(), blockType, diags)
+	}
+	if !collection.IsWhollyKnown() || collection.IsNull() || !collection.CanIterateElements() {
+		return nil, fmt.Errorf("%s: for_each of dynamic %q is not a known collection", dynamic.DefRange(), blockType)
+	}
+
+	var blocks []*NestedBlock
+	for it := collection.ElementIterator(); it.Next(); {
+		key, value := it.Element()
+		child := ctx.NewChild()
+		child.Variables = map[string]cty.Value{
+			iterator: cty.ObjectVal(map[string]cty.Value{"key": key, "value": value}),
+		}
+		block, err := evalNestedBlock(child, blockType, content.Body)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func evalNestedBlock(ctx *hcl.EvalContext, blockType string, body *hclsyntax.Body) (*NestedBlock, error) {
+	block := &NestedBlock{Type: blockType, Attributes: map[string]cty.Value{}}
+	for name, attr := range body.Attributes {
+		value, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s: %w", attr.SrcRange, diags)
+		}
+		block.Attributes[name] = value
+	}
+
+	seen := map[string]bool{}
+	for _, child := range body.Blocks {
+		childType := child.Type
+		if childType == "dynamic" && len(child.Labels) == 1 {
+			childType = child.Labels[0]
+		}
+		if seen[childType] {
+			continue
+		}
+		seen[childType] = true
+		children, err := expandBlocks(ctx, body, childType)
+		if err != nil {
+			return nil, err
+		}
+		block.Blocks = append(block.Blocks, children...)
+	}
+	return block, nil
+}
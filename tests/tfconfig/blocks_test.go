@@ -0,0 +1,93 @@
+package tfconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const blocksModule = `
+variable "flavor" {
+  type    = string
+  default = null
+}
+
+variable "disks" {
+  type    = list(number)
+  default = [10]
+}
+
+resource "ibm_database" "db" {
+  name = "db"
+
+  timeouts {
+    create = "1h"
+  }
+
+  dynamic "group" {
+    for_each = var.flavor == null ? [1] : []
+    content {
+      group_id = "member"
+      memory {
+        allocation_mb = 1024
+      }
+      dynamic "disk" {
+        for_each = var.disks
+        iterator = size
+        content {
+          allocation_mb = size.value
+          index         = size.key
+        }
+      }
+    }
+  }
+
+  dynamic "group" {
+    for_each = var.flavor != null ? [var.flavor] : []
+    content {
+      group_id = "member"
+      host_flavor {
+        id = group.value
+      }
+    }
+  }
+}
+`
+
+func TestNestedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(blocksModule), 0o644))
+	m, err := LoadModule(dir)
+	require.NoError(t, err)
+
+	groups, err := m.NestedBlocks(Inputs{"disks": "[10, 20]"}, "group", "resource", "ibm_database", "db")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, cty.StringVal("member"), groups[0].Attributes["group_id"])
+	require.Len(t, groups[0].Blocks, 3)
+	assert.Equal(t, "memory", groups[0].Blocks[0].Type)
+	disk := groups[0].Blocks[2]
+	assert.Equal(t, "disk", disk.Type)
+	assert.True(t, cty.NumberIntVal(20).Equals(disk.Attributes["allocation_mb"]).True())
+	assert.True(t, cty.NumberIntVal(1).Equals(disk.Attributes["index"]).True())
+
+	groups, err = m.NestedBlocks(Inputs{"flavor": `"b3c.4x16"`}, "group", "resource", "ibm_database", "db")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Blocks, 1)
+	assert.Equal(t, "host_flavor", groups[0].Blocks[0].Type)
+	assert.Equal(t, cty.StringVal("b3c.4x16"), groups[0].Blocks[0].Attributes["id"])
+
+	timeouts, err := m.NestedBlocks(Inputs{}, "timeouts", "resource", "ibm_database", "db")
+	require.NoError(t, err)
+	require.Len(t, timeouts, 1)
+	assert.Equal(t, cty.StringVal("1h"), timeouts[0].Attributes["create"])
+
+	none, err := m.NestedBlocks(Inputs{}, "auto_scaling", "resource", "ibm_database", "db")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
@@ -0,0 +1,528 @@
+package tfconfig
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// Inputs are input variable values written as HCL expressions, for example
+// Inputs{"plan": `"enterprise-gen2"`, "users": `[{name = "a", password = "b"}]`}.
+type Inputs map[string]string
+
+// Failure is a variable validation whose condition evaluated to false.
+type Failure struct {
+	Variable     string
+	ErrorMessage string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: %s", f.Variable, f.ErrorMessage)
+}
+
+// ErrKnownAfterApply is returned when an expression depends on resources, data sources or modules.
+var ErrKnownAfterApply = errors.New("depends on values that are only known after apply")
+
+// Values returns the value of every variable for the inputs: the parsed input converted to the variable type,
+// or the default. Null is replaced by the default for variables that are not nullable. Required variables that
+// have no input are unknown, so conditions that do not reference them can still be evaluated.
+func (m *Module) Values(inputs Inputs) (map[string]cty.Value, error) {
+	raw := map[string]cty.Value{}
+	for name, input := range inputs {
+		expr, diags := hclsyntax.ParseExpression([]byte(input), name+".input", hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("input %q: %w", name, diags)
+		}
+		value, diags := expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("input %q: %w", name, diags)
+		}
+		raw[name] = value
+	}
+	return m.ValuesFrom(raw)
+}
+
+// ValuesFrom is Values for inputs that are already evaluated, for example the arguments a calling module passes.
+// Unknown inputs stay unknown.
+func (m *Module) ValuesFrom(inputs map[string]cty.Value) (map[string]cty.Value, error) {
+	for name := range inputs {
+		if _, ok := m.Variables[name]; !ok {
+			return nil, fmt.Errorf("input %q is not a variable of %s", name, m.Dir)
+		}
+	}
+
+	values := map[string]cty.Value{}
+	for _, name := range m.VariableOrder {
+		variable := m.Variables[name]
+		raw, ok := inputs[name]
+		if !ok {
+			if variable.Required() {
+				values[name] = cty.UnknownVal(variable.Type)
+			} else {
+				values[name] = variable.Default
+			}
+			continue
+		}
+		if raw.IsKnown() && raw.IsNull() && !variable.Nullable && !variable.Required() {
+			values[name] = variable.Default
+			continue
+		}
+		value, err := variable.convert(raw)
+		if err != nil {
+			return nil, fmt.Errorf("input %q does not match type %s: %w", name, variable.Type.FriendlyName(), err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Locals evaluates every local value that only depends on input variables and other such locals.
+// Locals that depend on resources, data sources or modules are left out.
+func (m *Module) Locals(values map[string]cty.Value) map[string]cty.Value {
+	evaluated := map[string]cty.Value{}
+	for progress := true; progress; {
+		progress = false
+		for name, expr := range m.locals {
+			if _, done := evaluated[name]; done || !m.dependsOnlyOn(expr, evaluated) {
+				continue
+			}
+			value, diags := expr.Value(m.evalContext(values, evaluated))
+			if diags.HasErrors() {
+				continue
+			}
+			evaluated[name] = value
+			progress = true
+		}
+	}
+	return evaluated
+}
+
+func (m *Module) dependsOnlyOn(expr hcl.Expression, evaluated map[string]cty.Value, extraRoots ...string) bool {
+	for _, traversal := range expr.Variables() {
+		switch root := traversal.RootName(); {
+		case slices.Contains(extraRoots, root):
+		case root == "var":
+		case root == "local":
+			if len(traversal) < 2 {
+				return false
+			}
+			attr, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok {
+				return false
+			}
+			if _, ok := evaluated[attr.Name]; !ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Local evaluates a single local value for the inputs.
+func (m *Module) Local(name string, inputs Inputs) (cty.Value, error) {
+	if _, ok := m.locals[name]; !ok {
+		return cty.NilVal, fmt.Errorf("local %q is not declared in %s", name, m.Dir)
+	}
+	values, err := m.Values(inputs)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	value, ok := m.Locals(values)[name]
+	if !ok {
+		return cty.NilVal, fmt.Errorf("local %q %w", name, ErrKnownAfterApply)
+	}
+	return value, nil
+}
+
+// Validate evaluates every variable validation for the inputs and returns the ones that fail, in declaration order.
+// An error is returned if a condition cannot be evaluated.
+func (m *Module) Validate(inputs Inputs) ([]Failure, error) {
+	values, err := m.Values(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return m.ValidateValues(values)
+}
+
+// ValidateValues is Validate for values returned by Values or ValuesFrom.
+func (m *Module) ValidateValues(values map[string]cty.Value) ([]Failure, error) {
+	ctx := m.evalContext(values, m.Locals(values))
+
+	var failures []Failure
+	for _, name := range m.VariableOrder {
+		for _, validation := range m.Variables[name].Validations {
+			result, diags := validation.Condition.Value(ctx)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: evaluating validation of %q: %w", validation.DeclRange, name, diags)
+			}
+			if !result.IsKnown() {
+				return nil, fmt.Errorf("%s: validation of %q depends on a value that is not known: %s", validation.DeclRange, name, m.Source(validation.Condition))
+			}
+			if result.IsNull() || result.Type() != cty.Bool {
+				return nil, fmt.Errorf("%s: validation of %q did not evaluate to a bool", validation.DeclRange, name)
+			}
+			if result.True() {
+				continue
+			}
+			message, diags := validation.ErrorMessage.Value(ctx)
+			if diags.HasErrors() || message.Type() != cty.String || message.IsNull() {
+				return nil, fmt.Errorf("%s: error_message of %q is not a string", validation.DeclRange, name)
+			}
+			failures = append(failures, Failure{Variable: name, ErrorMessage: message.AsString()})
+		}
+	}
+	return failures, nil
+}
+
+// Evaluate evaluates an expression of the module for the inputs. It fails if the value depends on resources,
+// data sources or modules, because those are only known after apply.
+func (m *Module) Evaluate(expr hcl.Expression, inputs Inputs) (cty.Value, error) {
+	values, err := m.Values(inputs)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	locals := m.Locals(values)
+	if !m.dependsOnlyOn(expr, locals) {
+		return cty.NilVal, fmt.Errorf("%s: %s %w", expr.Range(), m.Source(expr), ErrKnownAfterApply)
+	}
+	value, diags := expr.Value(m.evalContext(values, locals))
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%s: %w", expr.Range(), diags)
+	}
+	return value, nil
+}
+
+// BlockAttribute evaluates an attribute of a top level block for the inputs, for example
+// BlockAttribute(inputs, "key_protect_key", "resource", "ibm_database", "elasticsearch").
+func (m *Module) BlockAttribute(inputs Inputs, name string, blockType string, labels ...string) (cty.Value, error) {
+	block, err := m.Block(blockType, labels...)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return cty.NilVal, fmt.Errorf("%s has no %q attribute", describe(blockType, labels), name)
+	}
+	return m.Evaluate(attr.Expr, inputs)
+}
+
+// ForEachAttribute evaluates an attribute of a top level block that uses for_each once per instance, with each.key
+// and each.value set. The values are keyed by instance key.
+func (m *Module) ForEachAttribute(inputs Inputs, name string, blockType string, labels ...string) (map[string]cty.Value, error) {
+	block, err := m.Block(blockType, labels...)
+	if err != nil {
+		return nil, err
+	}
+	forEach, ok := block.Body.Attributes["for_each"]
+	if !ok {
+		return nil, fmt.Errorf("%s has no for_each", describe(blockType, labels))
+	}
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return nil, fmt.Errorf("%s has no %q attribute", describe(blockType, labels), name)
+	}
+
+	values, err := m.Values(inputs)
+	if err != nil {
+		return nil, err
+	}
+	locals := m.Locals(values)
+	for _, expr := range []hcl.Expression{forEach.Expr, attr.Expr} {
+		if !m.dependsOnlyOn(expr, locals, "each") {
+			return nil, fmt.Errorf("%s: %s %w", expr.Range(), m.Source(expr), ErrKnownAfterApply)
+		}
+	}
+	ctx := m.evalContext(values, locals)
+	collection, diags := forEach.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s: %w", forEach.SrcRange, diags)
+	}
+	if !collection.IsWhollyKnown() || collection.IsNull() || !(collection.Type().IsMapType() || collection.Type().IsObjectType() || collection.Type().IsSetType()) {
+		return nil, fmt.Errorf("%s: for_each must be a known map or set", forEach.SrcRange)
+	}
+
+	instances := map[string]cty.Value{}
+	for it := collection.ElementIterator(); it.Next(); {
+		key, value := it.Element()
+		if collection.Type().IsSetType() {
+			key = value
+		}
+		child := ctx.NewChild()
+		child.Variables = map[string]cty.Value{"each": cty.ObjectVal(map[string]cty.Value{"key": key, "value": value})}
+		result, diags := attr.Expr.Value(child)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("%s: %w", attr.SrcRange, diags)
+		}
+		instances[key.AsString()] = result
+	}
+	return instances, nil
+}
+
+// Count returns the number of instances the count meta-argument of a resource or module block plans for the inputs.
+// Blocks without count have one instance.
+func (m *Module) Count(inputs Inputs, blockType string, labels ...string) (int, error) {
+	block, err := m.Block(blockType, labels...)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := block.Body.Attributes["for_each"]; ok {
+		return 0, fmt.Errorf("%s uses for_each, not count", describe(blockType, labels))
+	}
+	attr, ok := block.Body.Attributes["count"]
+	if !ok {
+		return 1, nil
+	}
+	value, err := m.Evaluate(attr.Expr, inputs)
+	if err != nil {
+		return 0, err
+	}
+	value, err = convert.Convert(value, cty.Number)
+	if err != nil || value.IsNull() || !value.IsKnown() {
+		return 0, fmt.Errorf("%s: count is not a known number", describe(blockType, labels))
+	}
+	count, _ := value.AsBigFloat().Int64()
+	return int(count), nil
+}
+
+// Output evaluates the value of an output for the inputs. Resources, data sources and module calls are unknown,
+// except that those with a count of 0 are empty tuples, so outputs that only read them behind can() or a
+// condition on their count still evaluate.
+func (m *Module) Output(inputs Inputs, name string) (cty.Value, error) {
+	block, err := m.Block("output", name)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	attr, ok := block.Body.Attributes["value"]
+	if !ok {
+		return cty.NilVal, fmt.Errorf("output %q has no value", name)
+	}
+	values, err := m.Values(inputs)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	ctx := m.evalContext(values, m.Locals(values))
+
+	objects := map[string]map[string]map[string]cty.Value{}
+	add := func(root, group, name string, value cty.Value) {
+		if objects[root] == nil {
+			objects[root] = map[string]map[string]cty.Value{}
+		}
+		if objects[root][group] == nil {
+			objects[root][group] = map[string]cty.Value{}
+		}
+		objects[root][group][name] = value
+	}
+	for _, traversal := range attr.Expr.Variables() {
+		steps := traversal.SimpleSplit().Rel
+		switch root := traversal.RootName(); {
+		case root == "var" || root == "local":
+		case root == "module" && len(steps) >= 1:
+			add(root, "", attrName(steps[0]), m.instances(inputs, "module", attrName(steps[0])))
+		case root == "data" && len(steps) >= 2:
+			add(root, attrName(steps[0]), attrName(steps[1]), m.instances(inputs, "data", attrName(steps[0]), attrName(steps[1])))
+		case len(steps) >= 1:
+			add(root, "", attrName(steps[0]), m.instances(inputs, "resource", root, attrName(steps[0])))
+		}
+	}
+	for root, groups := range objects {
+		if root == "data" {
+			types := map[string]cty.Value{}
+			for group, names := range groups {
+				types[group] = cty.ObjectVal(names)
+			}
+			ctx.Variables[root] = cty.ObjectVal(types)
+			continue
+		}
+		ctx.Variables[root] = cty.ObjectVal(groups[""])
+	}
+
+	value, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%s: %w", attr.SrcRange, diags)
+	}
+	return value, nil
+}
+
+// instances returns the value of a resource, data source or module call: unknown, or a tuple of unknown instances
+// when its count is known
+func (m *Module) instances(inputs Inputs, blockType string, labels ...string) cty.Value {
+	block, err := m.Block(blockType, labels...)
+	if err != nil {
+		return cty.DynamicVal
+	}
+	if _, ok := block.Body.Attributes["count"]; !ok {
+		return cty.DynamicVal
+	}
+	count, err := m.Count(inputs, blockType, labels...)
+	if err != nil {
+		return cty.DynamicVal
+	}
+	if count == 0 {
+		return cty.EmptyTupleVal
+	}
+	elements := make([]cty.Value, count)
+	for i := range elements {
+		elements[i] = cty.DynamicVal
+	}
+	return cty.TupleVal(elements)
+}
+
+func attrName(step hcl.Traverser) string {
+	if attr, ok := step.(hcl.TraverseAttr); ok {
+		return attr.Name
+	}
+	return ""
+}
+
+func (m *Module) evalContext(values map[string]cty.Value, locals map[string]cty.Value) *hcl.EvalContext {
+	localValues := map[string]cty.Value{}
+	for name := range m.locals {
+		localValues[name] = cty.DynamicVal
+	}
+	for name, value := range locals {
+		localValues[name] = value
+	}
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(values),
+			"local": cty.ObjectVal(localValues),
+		},
+		Functions: Functions,
+	}
+}
+
+// Functions are the Terraform built-in functions used by the configuration in this repository.
+var Functions = map[string]function.Function{
+	"alltrue":      allTrueFunc,
+	"anytrue":      anyTrueFunc,
+	"can":          tryfunc.CanFunc,
+	"coalesce":     stdlib.CoalesceFunc,
+	"concat":       stdlib.ConcatFunc,
+	"contains":     stdlib.ContainsFunc,
+	"endswith":     stringPredicateFunc(strings.HasSuffix),
+	"format":       stdlib.FormatFunc,
+	"join":         stdlib.JoinFunc,
+	"keys":         stdlib.KeysFunc,
+	"length":       lengthFunc,
+	"lookup":       stdlib.LookupFunc,
+	"lower":        stdlib.LowerFunc,
+	"nonsensitive": identityFunc,
+	"regex":        stdlib.RegexFunc,
+	"regexall":     stdlib.RegexAllFunc,
+	"split":        stdlib.SplitFunc,
+	"startswith":   stringPredicateFunc(strings.HasPrefix),
+	"substr":       stdlib.SubstrFunc,
+	"tobool":       makeToFunc(cty.Bool),
+	"tolist":       makeToFunc(cty.List(cty.DynamicPseudoType)),
+	"tomap":        makeToFunc(cty.Map(cty.DynamicPseudoType)),
+	"tonumber":     makeToFunc(cty.Number),
+	"toset":        makeToFunc(cty.Set(cty.DynamicPseudoType)),
+	"tostring":     makeToFunc(cty.String),
+	"trimspace":    stdlib.TrimSpaceFunc,
+	"try":          tryfunc.TryFunc,
+	"upper":        stdlib.UpperFunc,
+	"values":       stdlib.ValuesFunc,
+}
+
+// FunctionNames returns the names of the supported functions, sorted.
+func FunctionNames() []string {
+	names := make([]string, 0, len(Functions))
+	for name := range Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var allTrueFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "list", Type: cty.List(cty.Bool)}},
+	Type:   function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		result := cty.True
+		for it := args[0].ElementIterator(); it.Next(); {
+			_, v := it.Element()
+			if !v.IsKnown() {
+				return cty.UnknownVal(cty.Bool), nil
+			}
+			if v.IsNull() || v.False() {
+				result = cty.False
+			}
+		}
+		return result, nil
+	},
+})
+
+var anyTrueFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "list", Type: cty.List(cty.Bool)}},
+	Type:   function.StaticReturnType(cty.Bool),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		result := cty.False
+		for it := args[0].ElementIterator(); it.Next(); {
+			_, v := it.Element()
+			if !v.IsKnown() {
+				return cty.UnknownVal(cty.Bool), nil
+			}
+			if !v.IsNull() && v.True() {
+				result = cty.True
+			}
+		}
+		return result, nil
+	},
+})
+
+// lengthFunc accepts strings as well as collections, like the Terraform function
+var lengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "value", Type: cty.DynamicPseudoType, AllowDynamicType: true}},
+	Type:   function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		if args[0].Type() == cty.String {
+			return stdlib.Strlen(args[0])
+		}
+		return stdlib.Length(args[0])
+	},
+})
+
+var identityFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "value", Type: cty.DynamicPseudoType, AllowNull: true, AllowDynamicType: true, AllowUnknown: true}},
+	Type:   func(args []cty.Value) (cty.Type, error) { return args[0].Type(), nil },
+	Impl:   func(args []cty.Value, _ cty.Type) (cty.Value, error) { return args[0], nil },
+})
+
+// makeToFunc returns a type conversion function such as toset, converting element types as needed
+func makeToFunc(want cty.Type) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "value", Type: cty.DynamicPseudoType, AllowNull: true, AllowDynamicType: true}},
+		Type: func(args []cty.Value) (cty.Type, error) {
+			converted, err := convert.Convert(args[0], want)
+			if err != nil {
+				return cty.NilType, err
+			}
+			return converted.Type(), nil
+		},
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			return convert.Convert(args[0], want)
+		},
+	})
+}
+
+func stringPredicateFunc(predicate func(s, affix string) bool) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "str", Type: cty.String}, {Name: "affix", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.Bool),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			return cty.BoolVal(predicate(args[0].AsString(), args[1].AsString())), nil
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package tfconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PinnedVersion is a version literal that the configuration pins when a condition holds.
+type PinnedVersion struct {
+	Version   string
+	Condition string
+	File      string
+	Line      int
+}
+
+// Gen2DefaultVersion returns the version pinned for gen2 by an expression of the form
+// `local.is_gen2 ... ? "<version>" : var.elasticsearch_version` on the attribute of a top level block.
+func Gen2DefaultVersion(path string, attribute string, blockType string, labels ...string) (PinnedVersion, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return PinnedVersion{}, err
+	}
+	body, err := Parse(src, path)
+	if err != nil {
+		return PinnedVersion{}, err
+	}
+	attr, err := Attribute(body, attribute, blockType, labels...)
+	if err != nil {
+		return PinnedVersion{}, err
+	}
+	version, condition, err := ConditionalLiteral(attr.Expr, src)
+	if err != nil {
+		return PinnedVersion{}, fmt.Errorf("%s:%d: cannot find the pinned gen2 default version: %w", path, attr.SrcRange.Start.Line, err)
+	}
+	if !strings.Contains(condition, "is_gen2") {
+		return PinnedVersion{}, fmt.Errorf("%s:%d: version %q is pinned on condition %q, which does not check for gen2", path, attr.SrcRange.Start.Line, version, condition)
+	}
+	return PinnedVersion{Version: version, Condition: condition, File: path, Line: attr.SrcRange.Start.Line}, nil
+}
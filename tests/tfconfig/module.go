@@ -0,0 +1,212 @@
+package tfconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Module is the statically readable part of a Terraform module: its variables and locals.
+type Module struct {
+	Dir       string
+	Variables map[string]*Variable
+	// VariableOrder lists the variable names in declaration order
+	VariableOrder []string
+	// Files holds the parsed body of every .tf file, keyed by file name
+	Files map[string]*hclsyntax.Body
+
+	sources map[string][]byte
+	locals  map[string]hclsyntax.Expression
+}
+
+// Variable is a parsed `variable` block.
+type Variable struct {
+	Name        string
+	Description string
+	Type        cty.Type
+	Defaults    *typeexpr.Defaults
+	// Default is cty.NilVal when the variable has no default and is therefore required
+	Default     cty.Value
+	Nullable    bool
+	Sensitive   bool
+	Validations []*Validation
+	DeclRange   hcl.Range
+}
+
+// Required reports whether the variable has no default.
+func (v *Variable) Required() bool {
+	return v.Default == cty.NilVal
+}
+
+// Validation is a `validation` block of a variable.
+type Validation struct {
+	Condition    hclsyntax.Expression
+	ErrorMessage hclsyntax.Expression
+	DeclRange    hcl.Range
+}
+
+// LoadModule parses every .tf file in dir.
+func LoadModule(dir string) (*Module, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Terraform files found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	m := &Module{
+		Dir:       dir,
+		Variables: map[string]*Variable{},
+		Files:     map[string]*hclsyntax.Body{},
+		sources:   map[string][]byte{},
+		locals:    map[string]hclsyntax.Expression{},
+	}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		body, err := Parse(src, path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		m.Files[name] = body
+		m.sources[name] = src
+
+		for _, block := range body.Blocks {
+			switch block.Type {
+			case "variable":
+				variable, err := decodeVariable(block)
+				if err != nil {
+					return nil, err
+				}
+				if _, exists := m.Variables[variable.Name]; exists {
+					return nil, fmt.Errorf("%s: variable %q is declared more than once", block.DefRange(), variable.Name)
+				}
+				m.Variables[variable.Name] = variable
+				m.VariableOrder = append(m.VariableOrder, variable.Name)
+			case "locals":
+				for localName, attr := range block.Body.Attributes {
+					m.locals[localName] = attr.Expr
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// Source returns the source text of an expression declared in the module.
+func (m *Module) Source(expr hcl.Expression) string {
+	rng := expr.Range()
+	src, ok := m.sources[filepath.Base(rng.Filename)]
+	if !ok || rng.End.Byte > len(src) {
+		return ""
+	}
+	return string(rng.SliceBytes(src))
+}
+
+func decodeVariable(block *hclsyntax.Block) (*Variable, error) {
+	if len(block.Labels) != 1 {
+		return nil, fmt.Errorf("%s: variable block must have exactly one label", block.DefRange())
+	}
+	v := &Variable{
+		Name:      block.Labels[0],
+		Type:      cty.DynamicPseudoType,
+		Default:   cty.NilVal,
+		Nullable:  true,
+		DeclRange: block.DefRange(),
+	}
+	attrs := block.Body.Attributes
+
+	if attr, ok := attrs["type"]; ok {
+		ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(attr.Expr)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("variable %q: %w", v.Name, diags)
+		}
+		v.Type, v.Defaults = ty, defaults
+	}
+	if attr, ok := attrs["description"]; ok {
+		description, ok := StringLiteral(attr.Expr)
+		if !ok {
+			return nil, fmt.Errorf("variable %q: description must be a string literal", v.Name)
+		}
+		v.Description = description
+	}
+	for name, target := range map[string]*bool{"nullable": &v.Nullable, "sensitive": &v.Sensitive} {
+		attr, ok := attrs[name]
+		if !ok {
+			continue
+		}
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || value.Type() != cty.Bool || value.IsNull() {
+			return nil, fmt.Errorf("variable %q: %s must be a bool literal", v.Name, name)
+		}
+		*target = value.True()
+	}
+	if attr, ok := attrs["default"]; ok {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("variable %q: %w", v.Name, diags)
+		}
+		converted, err := v.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: default: %w", v.Name, err)
+		}
+		v.Default = converted
+	}
+	for _, child := range block.Body.Blocks {
+		if child.Type != "validation" {
+			continue
+		}
+		condition, hasCondition := child.Body.Attributes["condition"]
+		message, hasMessage := child.Body.Attributes["error_message"]
+		if !hasCondition || !hasMessage {
+			return nil, fmt.Errorf("%s: validation of variable %q must have a condition and an error_message", child.DefRange(), v.Name)
+		}
+		v.Validations = append(v.Validations, &Validation{Condition: condition.Expr, ErrorMessage: message.Expr, DeclRange: child.DefRange()})
+	}
+	return v, nil
+}
+
+// convert applies the optional attribute defaults of the type constraint and converts the value to it,
+// the same way Terraform prepares an input variable.
+func (v *Variable) convert(value cty.Value) (cty.Value, error) {
+	if value.IsNull() {
+		return cty.NullVal(v.Type), nil
+	}
+	if v.Defaults != nil {
+		value = v.Defaults.Apply(value)
+	}
+	return convert.Convert(value, v.Type)
+}
+
+// Block returns the single top level block with the type and labels from any file of the module.
+func (m *Module) Block(blockType string, labels ...string) (*hclsyntax.Block, error) {
+	var found *hclsyntax.Block
+	for _, body := range m.Files {
+		for _, block := range body.Blocks {
+			if block.Type != blockType || !slices.Equal(block.Labels, labels) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%s is declared more than once", describe(blockType, labels))
+			}
+			found = block
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s not found in %s", describe(blockType, labels), m.Dir)
+	}
+	return found, nil
+}
@@ -0,0 +1,272 @@
+package tfconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testModule = `
+locals {
+  is_gen2    = can(regex("-gen2$", var.plan))
+  is_classic = !local.is_gen2
+  instance   = ibm_database.db.id
+}
+
+variable "name" {
+  type = string
+}
+
+variable "plan" {
+  type    = string
+  default = "standard"
+  validation {
+    condition     = local.is_classic || var.size >= 10
+    error_message = "gen2 needs size 10 or more."
+  }
+}
+
+variable "size" {
+  type     = number
+  default  = 5
+  nullable = false
+}
+
+variable "credentials" {
+  type = list(object({
+    name = string
+    role = optional(string, "Viewer")
+  }))
+  default = []
+  validation {
+    condition     = alltrue([for c in var.credentials : contains(["Viewer", "Editor"], c.role)])
+    error_message = "role must be Viewer or Editor."
+  }
+  validation {
+    condition     = length(var.credentials) <= 2 && !anytrue([for c in var.credentials : startswith(c.name, "-")])
+    error_message = "at most 2 credentials, ${length(var.credentials)} given."
+  }
+}
+`
+
+func loadTestModule(t *testing.T) *Module {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testModule), 0o644))
+	m, err := LoadModule(dir)
+	require.NoError(t, err)
+	return m
+}
+
+func TestLoadModule(t *testing.T) {
+	m := loadTestModule(t)
+	assert.Equal(t, []string{"name", "plan", "size", "credentials"}, m.VariableOrder)
+	assert.True(t, m.Variables["name"].Required())
+	assert.False(t, m.Variables["size"].Nullable)
+	assert.Len(t, m.Variables["credentials"].Validations, 2)
+	assert.Equal(t, `local.is_classic || var.size >= 10`, m.Source(m.Variables["plan"].Validations[0].Condition))
+
+	_, err := LoadModule(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	m := loadTestModule(t)
+	tests := []struct {
+		name   string
+		inputs Inputs
+		want   []Failure
+	}{
+		{name: "defaults", inputs: Inputs{}},
+		{name: "local from another local", inputs: Inputs{"plan": `"standard-gen2"`}, want: []Failure{{"plan", "gen2 needs size 10 or more."}}},
+		{name: "other variable referenced", inputs: Inputs{"plan": `"standard-gen2"`, "size": "10"}},
+		{name: "null replaced by default when not nullable", inputs: Inputs{"plan": `"standard-gen2"`, "size": "null"}, want: []Failure{{"plan", "gen2 needs size 10 or more."}}},
+		{name: "optional attribute default applied", inputs: Inputs{"credentials": `[{name = "a"}]`}},
+		{name: "invalid role", inputs: Inputs{"credentials": `[{name = "a", role = "Administrator"}]`}, want: []Failure{{"credentials", "role must be Viewer or Editor."}}},
+		{
+			name:   "templated error message",
+			inputs: Inputs{"credentials": `[{name = "a"}, {name = "b"}, {name = "c"}]`},
+			want:   []Failure{{"credentials", "at most 2 credentials, 3 given."}},
+		},
+		{
+			name:   "several failures in declaration order",
+			inputs: Inputs{"plan": `"x-gen2"`, "credentials": `[{name = "-a", role = "Owner"}]`},
+			want: []Failure{
+				{"plan", "gen2 needs size 10 or more."},
+				{"credentials", "role must be Viewer or Editor."},
+				{"credentials", "at most 2 credentials, 1 given."},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failures, err := m.Validate(tc.inputs)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, failures)
+		})
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	m := loadTestModule(t)
+	for name, inputs := range map[string]Inputs{
+		"unknown variable":  {"missing": `"a"`},
+		"invalid syntax":    {"plan": `"a`},
+		"wrong type":        {"credentials": `"a"`},
+		"missing attribute": {"credentials": `[{role = "Viewer"}]`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := m.Validate(inputs)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLocals(t *testing.T) {
+	m := loadTestModule(t)
+	value, err := m.Local("is_classic", Inputs{"plan": `"enterprise-gen2"`})
+	require.NoError(t, err)
+	assert.Equal(t, cty.False, value)
+
+	_, err = m.Local("instance", Inputs{})
+	assert.ErrorContains(t, err, "known after apply")
+	_, err = m.Local("missing", Inputs{})
+	assert.Error(t, err)
+}
+
+func TestOutput(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testModule), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+resource "ibm_database" "db" {}
+data "ibm_database_connection" "classic" {
+  count = local.is_classic ? 1 : 0
+}
+module "rules" {
+  source = "./rules"
+  count  = var.size
+}
+output "id" {
+  value = ibm_database.db.id
+}
+output "hostname" {
+  value = can(data.ibm_database_connection.classic[0].hosts[0]) ? data.ibm_database_connection.classic[0].hosts[0] : null
+}
+output "rule_ids" {
+  value = module.rules[*].id
+}
+output "plan" {
+  value = local.is_gen2 ? "gen2" : var.plan
+}
+output "empty" {}
+`), 0o644))
+	m, err := LoadModule(dir)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		inputs Inputs
+		want   cty.Value
+	}{
+		{name: "id", want: cty.DynamicVal},
+		{name: "hostname", inputs: Inputs{"plan": `"x-gen2"`}, want: cty.NullVal(cty.DynamicPseudoType)},
+		{name: "hostname", want: cty.DynamicVal},
+		{name: "rule_ids", inputs: Inputs{"size": "0"}, want: cty.EmptyTupleVal},
+		{name: "rule_ids", inputs: Inputs{"size": "2"}, want: cty.TupleVal([]cty.Value{cty.DynamicVal, cty.DynamicVal})},
+		{name: "plan", inputs: Inputs{"plan": `"x-gen2"`}, want: cty.StringVal("gen2")},
+	} {
+		value, err := m.Output(tc.inputs, tc.name)
+		require.NoError(t, err)
+		assert.True(t, value.RawEquals(tc.want), "%s: got %#v, want %#v", tc.name, value, tc.want)
+	}
+
+	_, err = m.Output(Inputs{}, "empty")
+	assert.ErrorContains(t, err, "has no value")
+	_, err = m.Output(Inputs{}, "missing")
+	assert.Error(t, err)
+}
+
+func TestBlockAttributeAndCount(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testModule), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+resource "ibm_database" "db" {
+  plan = local.is_gen2 ? "gen2" : var.plan
+  name = module.names.name
+}
+resource "time_sleep" "wait" {
+  count = local.is_classic ? 0 : 2
+}
+resource "time_sleep" "each" {
+  for_each = toset(["a"])
+}
+module "names" {
+  source = "./names"
+}
+`), 0o644))
+	m, err := LoadModule(dir)
+	require.NoError(t, err)
+
+	value, err := m.BlockAttribute(Inputs{"plan": `"x-gen2"`}, "plan", "resource", "ibm_database", "db")
+	require.NoError(t, err)
+	assert.Equal(t, cty.StringVal("gen2"), value)
+	_, err = m.BlockAttribute(Inputs{}, "name", "resource", "ibm_database", "db")
+	assert.ErrorContains(t, err, "known after apply")
+	_, err = m.BlockAttribute(Inputs{}, "missing", "resource", "ibm_database", "db")
+	assert.Error(t, err)
+
+	count, err := m.Count(Inputs{}, "resource", "time_sleep", "wait")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	count, err = m.Count(Inputs{"plan": `"x-gen2"`}, "resource", "time_sleep", "wait")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = m.Count(Inputs{}, "module", "names")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	_, err = m.Count(Inputs{}, "resource", "time_sleep", "each")
+	assert.Error(t, err)
+	_, err = m.Count(Inputs{}, "resource", "time_sleep", "missing")
+	assert.Error(t, err)
+}
+
+func TestForEachAttribute(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(testModule), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+resource "ibm_resource_key" "keys" {
+  for_each             = { for c in var.credentials : c.name => c }
+  name                 = each.key
+  role                 = local.is_classic ? each.value.role : null
+  resource_instance_id = ibm_database.db.id
+}
+resource "time_sleep" "tags" {
+  for_each = toset(["a", "b"])
+  name     = "${each.key}-${each.value}"
+}
+`), 0o644))
+	m, err := LoadModule(dir)
+	require.NoError(t, err)
+
+	roles, err := m.ForEachAttribute(Inputs{"credentials": `[{name = "a"}, {name = "b", role = "Editor"}]`}, "role", "resource", "ibm_resource_key", "keys")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]cty.Value{"a": cty.StringVal("Viewer"), "b": cty.StringVal("Editor")}, roles)
+
+	roles, err = m.ForEachAttribute(Inputs{"plan": `"x-gen2"`, "credentials": `[{name = "a"}]`}, "role", "resource", "ibm_resource_key", "keys")
+	require.NoError(t, err)
+	assert.True(t, roles["a"].IsNull())
+
+	names, err := m.ForEachAttribute(Inputs{}, "name", "resource", "time_sleep", "tags")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]cty.Value{"a": cty.StringVal("a-a"), "b": cty.StringVal("b-b")}, names)
+
+	_, err = m.ForEachAttribute(Inputs{}, "resource_instance_id", "resource", "ibm_resource_key", "keys")
+	assert.ErrorIs(t, err, ErrKnownAfterApply)
+	_, err = m.ForEachAttribute(Inputs{}, "name", "resource", "ibm_database", "missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+// Package tfconfig reads the Terraform configuration in this repository with the HCL parser,
+// so tests can inspect it without running Terraform.
+package tfconfig
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ParseFile parses a Terraform file from disk.
+func ParseFile(path string) (*hclsyntax.Body, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(src, path)
+}
+
+// Parse parses Terraform source. The filename is only used in diagnostics.
+func Parse(src []byte, filename string) (*hclsyntax.Body, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return file.Body.(*hclsyntax.Body), nil
+}
+
+// Block returns the single top level block with the type and labels, for example
+// Block(body, "resource", "ibm_database", "elasticsearch").
+func Block(body *hclsyntax.Body, blockType string, labels ...string) (*hclsyntax.Block, error) {
+	var found *hclsyntax.Block
+	for _, block := range body.Blocks {
+		if block.Type != blockType || !slices.Equal(block.Labels, labels) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%s is declared more than once", describe(blockType, labels))
+		}
+		found = block
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s not found", describe(blockType, labels))
+	}
+	return found, nil
+}
+
+// Attribute returns the attribute with the name from the top level block with the type and labels.
+func Attribute(body *hclsyntax.Body, name string, blockType string, labels ...string) (*hclsyntax.Attribute, error) {
+	block, err := Block(body, blockType, labels...)
+	if err != nil {
+		return nil, err
+	}
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		return nil, fmt.Errorf("%s has no %q attribute", describe(blockType, labels), name)
+	}
+	return attr, nil
+}
+
+// StringLiteral returns the value of an expression that is a plain string literal such as "8.0".
+func StringLiteral(expr hclsyntax.Expression) (string, bool) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() || !value.IsKnown() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return "", false
+	}
+	return value.AsString(), true
+}
+
+// ConditionalLiteral returns the string literal from the branch of a `cond ? a : b` expression that is a
+// literal, along with the source text of the condition. It fails if the expression is not a conditional or
+// if neither or both branches are literals.
+func ConditionalLiteral(expr hclsyntax.Expression, src []byte) (string, string, error) {
+	conditional, ok := expr.(*hclsyntax.ConditionalExpr)
+	if !ok {
+		return "", "", fmt.Errorf("expression %q is not a conditional", Source(expr, src))
+	}
+	trueValue, trueIsLiteral := StringLiteral(conditional.TrueResult)
+	falseValue, falseIsLiteral := StringLiteral(conditional.FalseResult)
+	switch {
+	case trueIsLiteral && !falseIsLiteral:
+		return trueValue, Source(conditional.Condition, src), nil
+	case falseIsLiteral && !trueIsLiteral:
+		return falseValue, Source(conditional.Condition, src), nil
+	default:
+		return "", "", fmt.Errorf("expected exactly one string literal branch in %q", Source(expr, src))
+	}
+}
+
+// Source returns the source text of an expression.
+func Source(expr hclsyntax.Expression, src []byte) string {
+	rng := expr.Range()
+	if rng.End.Byte > len(src) || rng.Start.Byte > rng.End.Byte {
+		return ""
+	}
+	return string(rng.SliceBytes(src))
+}
+
+func describe(blockType string, labels []string) string {
+	if len(labels) == 0 {
+		return blockType
+	}
+	return fmt.Sprintf("%s %q", blockType, strings.Join(labels, `" "`))
+}
@@ -0,0 +1,86 @@
+package tfconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+func writeConfig(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.tf")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	return path
+}
+
+func TestBlockAndAttribute(t *testing.T) {
+	body, err := Parse([]byte(`
+resource "ibm_database" "elasticsearch" {
+  plan = "enterprise"
+}
+resource "ibm_database" "other" {}
+module "a" {}
+module "a" {}
+`), "main.tf")
+	require.NoError(t, err)
+
+	attr, err := Attribute(body, "plan", "resource", "ibm_database", "elasticsearch")
+	require.NoError(t, err)
+	value, ok := StringLiteral(attr.Expr)
+	assert.True(t, ok)
+	assert.Equal(t, "enterprise", value)
+
+	_, err = Attribute(body, "version", "resource", "ibm_database", "elasticsearch")
+	assert.Error(t, err)
+	_, err = Block(body, "resource", "ibm_database", "missing")
+	assert.Error(t, err)
+	_, err = Block(body, "module", "a")
+	assert.ErrorContains(t, err, "more than once")
+}
+
+func TestGen2DefaultVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{name: "literal in true branch", expr: `local.is_gen2 && var.elasticsearch_version == null ? "8.0" : var.elasticsearch_version`, want: "8.0"},
+		{name: "literal in false branch", expr: `!local.is_gen2 ? var.elasticsearch_version : "8.19"`, want: "8.19"},
+		{name: "not a conditional", expr: `var.elasticsearch_version`, wantErr: true},
+		{name: "no literal branch", expr: `local.is_gen2 ? var.a : var.elasticsearch_version`, wantErr: true},
+		{name: "condition not about gen2", expr: `var.elasticsearch_version == null ? "8.0" : var.elasticsearch_version`, wantErr: true},
+		{name: "interpolated template is not a literal", expr: `local.is_gen2 ? "${var.major}.0" : var.elasticsearch_version`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, "resource \"ibm_database\" \"elasticsearch\" {\n  version = "+tc.expr+"\n}\n")
+			pinned, err := Gen2DefaultVersion(path, "version", "resource", "ibm_database", "elasticsearch")
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, pinned.Version)
+			assert.Equal(t, 2, pinned.Line)
+		})
+	}
+}
+
+// The pinned defaults in this repository must stay detectable
+func TestRepositoryGen2Defaults(t *testing.T) {
+	pinned, err := Gen2DefaultVersion("../../main.tf", "version", "resource", "ibm_database", "elasticsearch")
+	require.NoError(t, err)
+	_, err = versions.Parse(pinned.Version)
+	assert.NoError(t, err)
+
+	example, err := Gen2DefaultVersion("../../examples/basic/main.tf", "elasticsearch_version", "module", "database")
+	require.NoError(t, err)
+	assert.Equal(t, pinned.Version, example.Version, "the basic example pins a different gen2 version than the module")
+}
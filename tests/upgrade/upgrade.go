@@ -0,0 +1,136 @@
+// Package upgrade computes the chain of Elasticsearch version upgrades between two offered versions
+// and applies it hop by hop to a deployed instance.
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/versions"
+)
+
+// Hop is a single in-place version upgrade.
+type Hop struct {
+	From string
+	To   string
+}
+
+func (h Hop) String() string {
+	return h.From + " -> " + h.To
+}
+
+// Chain returns the hops needed to upgrade from one offered version to another. Every offered version
+// in between is visited in ascending order, so a major version is only entered from the newest offered
+// minor of the previous major, and no major version can be skipped.
+func Chain(offered []string, from string, to string) ([]Hop, error) {
+	list, err := versions.ParseAll(offered)
+	if err != nil {
+		return nil, err
+	}
+	start, err := versions.Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := versions.Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	if !end.LessThan(start) && !start.LessThan(end) {
+		return nil, nil
+	}
+	if end.LessThan(start) {
+		return nil, fmt.Errorf("cannot upgrade from %s to older version %s", start, end)
+	}
+
+	// keep the offered versions within the range, dropping duplicates such as "8.19" and "8.19.0"
+	var steps []versions.ElasticsearchVersion
+	foundStart, foundEnd := false, false
+	for _, version := range versions.Sorted(list) {
+		if version.LessThan(start) || end.LessThan(version) {
+			continue
+		}
+		if len(steps) > 0 && steps[len(steps)-1].Compare(version) == 0 {
+			continue
+		}
+		foundStart = foundStart || version.Compare(start) == 0
+		foundEnd = foundEnd || version.Compare(end) == 0
+		steps = append(steps, version)
+	}
+	if !foundStart {
+		return nil, fmt.Errorf("version %s is not offered", start)
+	}
+	if !foundEnd {
+		return nil, fmt.Errorf("version %s is not offered", end)
+	}
+
+	hops := make([]Hop, 0, len(steps)-1)
+	for i := 1; i < len(steps); i++ {
+		if steps[i].Major > steps[i-1].Major+1 {
+			return nil, fmt.Errorf("cannot upgrade from %s to %s: no offered version of major %d in between", steps[i-1], steps[i], steps[i-1].Major+1)
+		}
+		hops = append(hops, Hop{From: steps[i-1].String(), To: steps[i].String()})
+	}
+	return hops, nil
+}
+
+// CheckInPlace returns an error unless the plan updates the resource at address in place.
+func CheckInPlace(plan *terraform.PlanStruct, address string) error {
+	change, ok := plan.ResourceChangesMap[address]
+	if !ok || change.Change == nil {
+		return fmt.Errorf("plan has no change for %s", address)
+	}
+	actions := change.Change.Actions
+	if actions.Replace() || actions.Delete() || actions.Create() {
+		return fmt.Errorf("%s would be replaced instead of updated in place: %v", address, actions)
+	}
+	if !actions.Update() {
+		return fmt.Errorf("%s is not updated by the plan: %v", address, actions)
+	}
+	return nil
+}
+
+// SkipBackup returns the `version_upgrade_skip_backup` value used for the hop at index. Hops alternate
+// between taking a backup and skipping it, starting with a backup.
+func SkipBackup(index int) bool {
+	return index%2 == 1
+}
+
+// Apply upgrades an already deployed instance through every hop in order. Each hop is planned first and
+// the saved plan is only applied if it updates the instance at address in place. After every hop the
+// `version` output must report the new version.
+func Apply(t *testing.T, ctx context.Context, options *terraform.Options, address string, hops []Hop) error {
+	defer func() { options.PlanFilePath = "" }()
+
+	for i, hop := range hops {
+		logger.Log(t, fmt.Sprintf("Upgrade hop %d/%d: %s (version_upgrade_skip_backup=%t)", i+1, len(hops), hop, SkipBackup(i)))
+		options.Vars["elasticsearch_version"] = hop.To
+		options.Vars["version_upgrade_skip_backup"] = SkipBackup(i)
+		options.PlanFilePath = filepath.Join(options.TerraformDir, fmt.Sprintf("upgrade-hop-%d.tfplan", i+1))
+
+		plan, err := terraform.InitAndPlanAndShowWithStructContextE(t, ctx, options)
+		if err != nil {
+			return fmt.Errorf("planning upgrade %s: %w", hop, err)
+		}
+		if err := CheckInPlace(plan, address); err != nil {
+			return fmt.Errorf("upgrade %s: %w", hop, err)
+		}
+		if _, err := terraform.ApplyContextE(t, ctx, options); err != nil {
+			return fmt.Errorf("applying upgrade %s: %w", hop, err)
+		}
+		options.PlanFilePath = ""
+
+		deployed, err := terraform.OutputContextE(t, ctx, options, "version")
+		if err != nil {
+			return fmt.Errorf("reading version after upgrade %s: %w", hop, err)
+		}
+		if deployed != hop.To {
+			return fmt.Errorf("upgrade %s: instance reports version %s", hop, deployed)
+		}
+	}
+	return nil
+}
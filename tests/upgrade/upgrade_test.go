@@ -0,0 +1,103 @@
+package upgrade
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered []string
+		from    string
+		to      string
+		want    []Hop
+		wantErr bool
+	}{
+		{
+			name:    "every offered minor is visited",
+			offered: []string{"8.19", "8.12", "8.15"},
+			from:    "8.12",
+			to:      "8.19",
+			want:    []Hop{{From: "8.12", To: "8.15"}, {From: "8.15", To: "8.19"}},
+		},
+		{
+			name:    "major upgrade only from the newest minor of the previous major",
+			offered: []string{"8.12", "8.15", "8.19", "9.1"},
+			from:    "8.15",
+			to:      "9.1",
+			want:    []Hop{{From: "8.15", To: "8.19"}, {From: "8.19", To: "9.1"}},
+		},
+		{
+			name:    "versions outside the range are ignored",
+			offered: []string{"8.7", "8.10", "8.12", "8.15", "9.1"},
+			from:    "8.10",
+			to:      "8.12",
+			want:    []Hop{{From: "8.10", To: "8.12"}},
+		},
+		{
+			name:    "equivalent versions are not separate hops",
+			offered: []string{"8.15", "8.19", "8.19.0"},
+			from:    "8.15",
+			to:      "8.19",
+			want:    []Hop{{From: "8.15", To: "8.19"}},
+		},
+		{
+			name:    "patch releases are separate hops",
+			offered: []string{"8.19", "8.19.11"},
+			from:    "8.19",
+			to:      "8.19.11",
+			want:    []Hop{{From: "8.19", To: "8.19.11"}},
+		},
+		{
+			name:    "already on the target version",
+			offered: []string{"8.15", "8.19"},
+			from:    "8.19",
+			to:      "8.19",
+			want:    nil,
+		},
+		{name: "downgrade", offered: []string{"8.15", "8.19"}, from: "8.19", to: "8.15", wantErr: true},
+		{name: "start not offered", offered: []string{"8.15", "8.19"}, from: "8.12", to: "8.19", wantErr: true},
+		{name: "target not offered", offered: []string{"8.15", "8.19"}, from: "8.15", to: "9.1", wantErr: true},
+		{name: "skipping a major", offered: []string{"8.19", "10.0"}, from: "8.19", to: "10.0", wantErr: true},
+		{name: "invalid offered version", offered: []string{"8.19", "nine"}, from: "8.19", to: "8.19", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Chain(tc.offered, tc.from, tc.to)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCheckInPlace(t *testing.T) {
+	const address = "module.elasticsearch[0].ibm_database.elasticsearch"
+	plan := func(actions ...tfjson.Action) *terraform.PlanStruct {
+		return &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+			address: {Address: address, Change: &tfjson.Change{Actions: actions}},
+		}}
+	}
+
+	assert.NoError(t, CheckInPlace(plan(tfjson.ActionUpdate), address))
+	assert.Error(t, CheckInPlace(plan(tfjson.ActionDelete, tfjson.ActionCreate), address))
+	assert.Error(t, CheckInPlace(plan(tfjson.ActionCreate, tfjson.ActionDelete), address))
+	assert.Error(t, CheckInPlace(plan(tfjson.ActionNoop), address))
+	assert.Error(t, CheckInPlace(plan(tfjson.ActionUpdate), "ibm_database.other"))
+}
+
+func TestSkipBackup(t *testing.T) {
+	assert.False(t, SkipBackup(0))
+	assert.True(t, SkipBackup(1))
+	assert.False(t, SkipBackup(2))
+}
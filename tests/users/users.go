@@ -0,0 +1,174 @@
+// Package users verifies the database users of the users input and the service credentials of the
+// service_credential_names input on a deployed instance: that each one authenticates, and that its role grants the
+// expected access to indices.
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch"
+)
+
+// User is an entry of the users input.
+type User struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Type     string `json:"type,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// ServiceCredential is an entry of the service_credential_names input.
+type ServiceCredential struct {
+	Name     string `json:"name"`
+	Role     string `json:"role,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Access is the access to indices that an account must have.
+type Access string
+
+const (
+	// Authenticates only checks that the account authenticates
+	Authenticates Access = "authenticates"
+	// ReadOnly reads indices and cannot write them
+	ReadOnly Access = "read-only"
+	// ReadWrite reads and writes indices
+	ReadWrite Access = "read-write"
+)
+
+// RoleAccess returns the access that a service credential role grants. Viewer reads indices; Editor and
+// Administrator on classic instances and Writer and Manager on gen2 instances write them. Operator manages the
+// instance rather than its data, so only its authentication is checked.
+func RoleAccess(role string) Access {
+	switch role {
+	case "", "Viewer":
+		// Viewer is the default role of service_credential_names
+		return ReadOnly
+	case "Editor", "Administrator", "Writer", "Manager":
+		return ReadWrite
+	}
+	return Authenticates
+}
+
+// UserAccess returns the access that a database user of the users input must have. A user without a role has the
+// privileges of the admin user, as the database users of an Elasticsearch instance do; a role is read like a
+// service credential role.
+func UserAccess(role string) Access {
+	if role == "" {
+		return ReadWrite
+	}
+	return RoleAccess(role)
+}
+
+// Account is a login to verify.
+type Account struct {
+	// Name is the name of the users entry or of the service credential
+	Name        string
+	Credentials credentials.Credentials
+	Access      Access
+}
+
+// Accounts returns the accounts to verify: a database user of each users entry, which connects to the instance
+// like connection and has the access of its role, and the service credential of each service_credential_names entry
+// from the decoded service_credentials_object output.
+func Accounts(users []User, serviceCredentials []ServiceCredential, object map[string]credentials.Credentials, connection credentials.Credentials) ([]Account, error) {
+	var accounts []Account
+	for _, u := range users {
+		c := connection
+		c.Name, c.Username, c.Password = u.Name, u.Name, u.Password
+		accounts = append(accounts, Account{Name: u.Name, Credentials: c, Access: UserAccess(u.Role)})
+	}
+	var missing []string
+	for _, sc := range serviceCredentials {
+		c, ok := object[sc.Name]
+		if !ok {
+			missing = append(missing, sc.Name)
+			continue
+		}
+		accounts = append(accounts, Account{Name: sc.Name, Credentials: c, Access: RoleAccess(sc.Role)})
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("service_credentials_object has no credentials %v", missing)
+	}
+	return accounts, nil
+}
+
+// Result is what the cluster reports about an account.
+type Result struct {
+	Account Account
+	// Username and Roles are the user the account authenticates as and its roles
+	Username string
+	Roles    []string
+	// Read and Write are whether the account has the read and write privileges on the index
+	Read  bool
+	Write bool
+}
+
+// Verify authenticates each account with _security/_authenticate and checks its access with
+// _security/user/_has_privileges on the index, which does not have to exist. The privileges are asked for rather
+// than exercised, so nothing is written. The errors of all the accounts are returned together.
+func Verify(ctx context.Context, accounts []Account, index string) ([]Result, error) {
+	var results []Result
+	var errs []error
+	for _, account := range accounts {
+		result, err := verify(ctx, account, index)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.Name, err))
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+func verify(ctx context.Context, account Account, index string) (Result, error) {
+	client, err := elasticsearch.New(account.Credentials)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Account: account}
+
+	var authenticated struct {
+		Username string   `json:"username"`
+		Roles    []string `json:"roles"`
+	}
+	if err := client.Do(ctx, http.MethodGet, "/_security/_authenticate", nil, &authenticated); err != nil {
+		return result, fmt.Errorf("authenticating as %s: %w", account.Credentials.Username, err)
+	}
+	result.Username, result.Roles = authenticated.Username, authenticated.Roles
+	if account.Access == Authenticates {
+		return result, nil
+	}
+
+	request := map[string]any{"index": []any{map[string]any{"names": []string{index}, "privileges": []string{"read", "write"}}}}
+	var privileges struct {
+		Index map[string]struct {
+			Read  bool `json:"read"`
+			Write bool `json:"write"`
+		} `json:"index"`
+	}
+	if err := client.Do(ctx, http.MethodPost, "/_security/user/_has_privileges", request, &privileges); err != nil {
+		return result, fmt.Errorf("reading the privileges of %s: %w", result.Username, err)
+	}
+	granted, ok := privileges.Index[index]
+	if !ok {
+		return result, fmt.Errorf("the privileges of %s have no index %s", result.Username, index)
+	}
+	result.Read, result.Write = granted.Read, granted.Write
+
+	switch {
+	case !result.Read:
+		return result, fmt.Errorf("%s with roles %v cannot read %s, expected %s", result.Username, result.Roles, index, account.Access)
+	case account.Access == ReadOnly && result.Write:
+		return result, fmt.Errorf("%s with roles %v can write %s, expected %s", result.Username, result.Roles, index, account.Access)
+	case account.Access == ReadWrite && !result.Write:
+		return result, fmt.Errorf("%s with roles %v cannot write %s, expected %s", result.Username, result.Roles, index, account.Access)
+	}
+	return result, nil
+}
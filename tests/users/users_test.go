@@ -0,0 +1,130 @@
+package users
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/credentials"
+	"github.com/terraform-ibm-modules/terraform-ibm-icd-elasticsearch/elasticsearch/elasticsearchtest"
+)
+
+func TestRoleAccess(t *testing.T) {
+	tests := map[string]Access{
+		"":              ReadOnly,
+		"Viewer":        ReadOnly,
+		"Editor":        ReadWrite,
+		"Administrator": ReadWrite,
+		"Writer":        ReadWrite,
+		"Manager":       ReadWrite,
+		"Operator":      Authenticates,
+	}
+	for role, want := range tests {
+		assert.Equal(t, want, RoleAccess(role), role)
+	}
+}
+
+func TestUserAccess(t *testing.T) {
+	tests := map[string]Access{
+		"":              ReadWrite,
+		"Viewer":        ReadOnly,
+		"Editor":        ReadWrite,
+		"Administrator": ReadWrite,
+		"Operator":      Authenticates,
+	}
+	for role, want := range tests {
+		assert.Equal(t, want, UserAccess(role), role)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	connection := credentials.Credentials{Name: "admin", Username: "admin", Password: "admin-password", Hostname: "public.host", Port: 31234, CertificateBase64: "Y2VydA=="} // pragma: allowlist secret
+	object := map[string]credentials.Credentials{
+		"es_viewer": {Name: "es_viewer", Username: "u1", Password: "p1", Hostname: "public.host", Port: 31234}, // pragma: allowlist secret
+		"es_editor": {Name: "es_editor", Username: "u2", Password: "p2", Hostname: "public.host", Port: 31234}, // pragma: allowlist secret
+	}
+
+	accounts, err := Accounts(
+		[]User{{Name: "testuser", Password: "testuser-password", Type: "database"}, {Name: "reader", Password: "reader-password", Type: "database", Role: "Viewer"}}, // pragma: allowlist secret
+		[]ServiceCredential{{Name: "es_viewer", Role: "Viewer"}, {Name: "es_editor", Role: "Editor", Endpoint: "public"}},
+		object, connection)
+	require.NoError(t, err)
+	assert.Equal(t, []Account{
+		{Name: "testuser", Credentials: credentials.Credentials{Name: "testuser", Username: "testuser", Password: "testuser-password", Hostname: "public.host", Port: 31234, CertificateBase64: "Y2VydA=="}, Access: ReadWrite}, // pragma: allowlist secret
+		{Name: "reader", Credentials: credentials.Credentials{Name: "reader", Username: "reader", Password: "reader-password", Hostname: "public.host", Port: 31234, CertificateBase64: "Y2VydA=="}, Access: ReadOnly},          // pragma: allowlist secret
+		{Name: "es_viewer", Credentials: object["es_viewer"], Access: ReadOnly},
+		{Name: "es_editor", Credentials: object["es_editor"], Access: ReadWrite},
+	}, accounts)
+
+	_, err = Accounts(nil, []ServiceCredential{{Name: "es_viewer"}, {Name: "es_operator"}, {Name: "es_admin"}}, object, connection)
+	assert.EqualError(t, err, "service_credentials_object has no credentials [es_admin es_operator]")
+}
+
+func TestVerify(t *testing.T) {
+	server := elasticsearchtest.NewServer(elasticsearchtest.Options{Users: map[string]string{"admin": "admin-password"}}) // pragma: allowlist secret
+	t.Cleanup(server.Close)
+	server.AddUser("testuser", "testuser-password", "editor") // pragma: allowlist secret
+	server.AddUser("viewer", "viewer-password", "viewer")     // pragma: allowlist secret
+	server.AddUser("editor", "editor-password", "editor")     // pragma: allowlist secret
+
+	account := func(name string, username string, access Access) Account {
+		return Account{Name: name, Credentials: server.Credentials(username), Access: access}
+	}
+	wrongPassword := account("es_admin", "admin", ReadWrite)
+	wrongPassword.Credentials.Password = "wrong" // pragma: allowlist secret
+
+	tests := []struct {
+		name        string
+		accounts    []Account
+		wantResults []Result
+		wantErr     []string
+	}{
+		{
+			name: "every account has its access",
+			accounts: []Account{
+				account("testuser", "testuser", ReadWrite),
+				account("es_viewer", "viewer", ReadOnly),
+				account("es_editor", "editor", ReadWrite),
+				account("es_admin", "admin", ReadWrite),
+			},
+			wantResults: []Result{
+				{Account: account("testuser", "testuser", ReadWrite), Username: "testuser", Roles: []string{"editor"}, Read: true, Write: true},
+				{Account: account("es_viewer", "viewer", ReadOnly), Username: "viewer", Roles: []string{"viewer"}, Read: true},
+				{Account: account("es_editor", "editor", ReadWrite), Username: "editor", Roles: []string{"editor"}, Read: true, Write: true},
+				{Account: account("es_admin", "admin", ReadWrite), Username: "admin", Roles: []string{"superuser"}, Read: true, Write: true},
+			},
+		},
+		{
+			name:        "viewer can write",
+			accounts:    []Account{account("es_viewer", "editor", ReadOnly)},
+			wantResults: []Result{},
+			wantErr:     []string{"es_viewer: editor with roles [editor] can write users-verify, expected read-only"},
+		},
+		{
+			name:        "errors of every account",
+			accounts:    []Account{account("es_editor", "viewer", ReadWrite), wrongPassword, account("es_viewer", "viewer", ReadOnly)},
+			wantResults: []Result{{Account: account("es_viewer", "viewer", ReadOnly), Username: "viewer", Roles: []string{"viewer"}, Read: true}},
+			wantErr: []string{
+				"es_editor: viewer with roles [viewer] cannot write users-verify, expected read-write",
+				"es_admin: authenticating as admin: GET /_security/_authenticate: 401 Unauthorized",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := Verify(context.Background(), tc.accounts, "users-verify")
+			if len(tc.wantErr) == 0 {
+				require.NoError(t, err)
+			}
+			for _, want := range tc.wantErr {
+				assert.ErrorContains(t, err, want)
+			}
+			assert.ElementsMatch(t, tc.wantResults, results)
+		})
+	}
+	_, ok := server.Documents("users-verify")
+	assert.False(t, ok, "nothing is written")
+}
@@ -0,0 +1,184 @@
+// Package versions parses and orders the Databases for Elasticsearch versions returned by the IBM Cloud catalog.
+package versions
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ElasticsearchVersion is a parsed `major[.minor[.patch]]` Elasticsearch version.
+// Missing components compare as zero, so "8.19" and "8.19.0" are equal.
+type ElasticsearchVersion struct {
+	Major int
+	Minor int
+	Patch int
+
+	// parts is the number of components in the original value, used to render it back unchanged
+	parts int
+}
+
+// Parse parses a version such as "8", "8.19" or "8.19.11". Anything else, including signs,
+// whitespace, empty components or more than three components, is rejected.
+func Parse(value string) (ElasticsearchVersion, error) {
+	if value == "" {
+		return ElasticsearchVersion{}, fmt.Errorf("invalid elasticsearch version %q: empty value", value)
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return ElasticsearchVersion{}, fmt.Errorf("invalid elasticsearch version %q: expected at most 3 components, got %d", value, len(parts))
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		if part == "" {
+			return ElasticsearchVersion{}, fmt.Errorf("invalid elasticsearch version %q: empty component at position %d", value, i)
+		}
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return ElasticsearchVersion{}, fmt.Errorf("invalid elasticsearch version %q: component %q is not a number", value, part)
+			}
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return ElasticsearchVersion{}, fmt.Errorf("invalid elasticsearch version %q: %w", value, err)
+		}
+		numbers[i] = number
+	}
+
+	return ElasticsearchVersion{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], parts: len(parts)}, nil
+}
+
+// MustParse is like Parse but panics if the value cannot be parsed.
+func MustParse(value string) ElasticsearchVersion {
+	version, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return version
+}
+
+// ParseAll parses every value in the list and fails on the first invalid one.
+func ParseAll(values []string) ([]ElasticsearchVersion, error) {
+	parsed := make([]ElasticsearchVersion, 0, len(values))
+	for _, value := range values {
+		version, err := Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, version)
+	}
+	return parsed, nil
+}
+
+// String returns the version with the same number of components it was parsed with.
+func (v ElasticsearchVersion) String() string {
+	switch v.parts {
+	case 1:
+		return strconv.Itoa(v.Major)
+	case 3:
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	default:
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+}
+
+// Compare returns -1 if v is older than other, 1 if it is newer and 0 if they are equal.
+func (v ElasticsearchVersion) Compare(other ElasticsearchVersion) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt(v.Minor, other.Minor)
+	default:
+		return compareInt(v.Patch, other.Patch)
+	}
+}
+
+// LessThan reports whether v is older than other.
+func (v ElasticsearchVersion) LessThan(other ElasticsearchVersion) bool {
+	return v.Compare(other) < 0
+}
+
+// Matches reports whether other is v or a more specific version of it, so "8.19" matches the "8.19.3" that a
+// cluster reports but not "8.15.3", and "8.19.3" only matches "8.19.3".
+func (v ElasticsearchVersion) Matches(other ElasticsearchVersion) bool {
+	mine := []int{v.Major, v.Minor, v.Patch}
+	theirs := []int{other.Major, other.Minor, other.Patch}
+	for i := 0; i < v.parts; i++ {
+		if mine[i] != theirs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Sort orders the versions from oldest to newest in place.
+func Sort(list []ElasticsearchVersion) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].LessThan(list[j])
+	})
+}
+
+// Sorted returns a copy of the versions ordered from oldest to newest.
+func Sorted(list []ElasticsearchVersion) []ElasticsearchVersion {
+	sorted := append([]ElasticsearchVersion(nil), list...)
+	Sort(sorted)
+	return sorted
+}
+
+// Latest returns the newest version in the list.
+func Latest(list []ElasticsearchVersion) (ElasticsearchVersion, error) {
+	return NthNewest(list, 0)
+}
+
+// Oldest returns the oldest version in the list.
+func Oldest(list []ElasticsearchVersion) (ElasticsearchVersion, error) {
+	if len(list) == 0 {
+		return ElasticsearchVersion{}, fmt.Errorf("no elasticsearch versions available")
+	}
+	return Sorted(list)[0], nil
+}
+
+// NthNewest returns the n-th newest version in the list, where 0 is the latest version,
+// 1 the one before it and so on.
+func NthNewest(list []ElasticsearchVersion, n int) (ElasticsearchVersion, error) {
+	if len(list) == 0 {
+		return ElasticsearchVersion{}, fmt.Errorf("no elasticsearch versions available")
+	}
+	if n < 0 || n >= len(list) {
+		return ElasticsearchVersion{}, fmt.Errorf("cannot select version %d from the newest: only %d versions available", n, len(list))
+	}
+	sorted := Sorted(list)
+	return sorted[len(sorted)-1-n], nil
+}
+
+// LatestAndOldest parses the raw catalog values and returns the newest and oldest of them.
+func LatestAndOldest(values []string) (ElasticsearchVersion, ElasticsearchVersion, error) {
+	list, err := ParseAll(values)
+	if err != nil {
+		return ElasticsearchVersion{}, ElasticsearchVersion{}, err
+	}
+	latest, err := Latest(list)
+	if err != nil {
+		return ElasticsearchVersion{}, ElasticsearchVersion{}, err
+	}
+	oldest, err := Oldest(list)
+	if err != nil {
+		return ElasticsearchVersion{}, ElasticsearchVersion{}, err
+	}
+	return latest, oldest, nil
+}
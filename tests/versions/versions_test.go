@@ -0,0 +1,156 @@
+package versions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ElasticsearchVersion
+		wantErr bool
+	}{
+		{value: "8", want: ElasticsearchVersion{Major: 8, parts: 1}},
+		{value: "8.19", want: ElasticsearchVersion{Major: 8, Minor: 19, parts: 2}},
+		{value: "8.19.11", want: ElasticsearchVersion{Major: 8, Minor: 19, Patch: 11, parts: 3}},
+		{value: "9.1", want: ElasticsearchVersion{Major: 9, Minor: 1, parts: 2}},
+		{value: "", wantErr: true},
+		{value: "8.", wantErr: true},
+		{value: ".19", wantErr: true},
+		{value: "8..1", wantErr: true},
+		{value: "v8.19", wantErr: true},
+		{value: "8.19-beta", wantErr: true},
+		{value: "-8.19", wantErr: true},
+		{value: "+8.19", wantErr: true},
+		{value: " 8.19", wantErr: true},
+		{value: "8.19.11.1", wantErr: true},
+		{value: "99999999999999999999.1", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := Parse(tc.value)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.value, got.String())
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "8.19", b: "8.19", want: 0},
+		{a: "8.19", b: "8.19.0", want: 0},
+		{a: "8.7", b: "8.10", want: -1},
+		{a: "8.19", b: "8.19.11", want: -1},
+		{a: "8.19.11", b: "8.19.2", want: 1},
+		{a: "9.1", b: "8.19", want: 1},
+		{a: "8", b: "8.1", want: -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			a, b := MustParse(tc.a), MustParse(tc.b)
+			assert.Equal(t, tc.want, a.Compare(b))
+			assert.Equal(t, -tc.want, b.Compare(a))
+			assert.Equal(t, tc.want < 0, a.LessThan(b))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "8.19", b: "8.19.3", want: true},
+		{a: "8.19", b: "8.19", want: true},
+		{a: "8", b: "8.19.3", want: true},
+		{a: "8.19.3", b: "8.19.3", want: true},
+		{a: "8.19.3", b: "8.19.2", want: false},
+		{a: "8.19", b: "8.15.3", want: false},
+		{a: "8.19", b: "9.19.0", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			assert.Equal(t, tc.want, MustParse(tc.a).Matches(MustParse(tc.b)))
+		})
+	}
+}
+
+func TestSelection(t *testing.T) {
+	list, err := ParseAll([]string{"8.15", "9.1", "8.7", "8.19.11", "8.10", "8.19", "8.12"})
+	require.NoError(t, err)
+
+	latest, err := Latest(list)
+	require.NoError(t, err)
+	assert.Equal(t, "9.1", latest.String())
+
+	oldest, err := Oldest(list)
+	require.NoError(t, err)
+	assert.Equal(t, "8.7", oldest.String())
+
+	second, err := NthNewest(list, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "8.19.11", second.String())
+
+	third, err := NthNewest(list, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "8.19", third.String())
+
+	_, err = NthNewest(list, len(list))
+	assert.Error(t, err)
+	_, err = NthNewest(list, -1)
+	assert.Error(t, err)
+
+	// selection must not reorder the caller's slice
+	assert.Equal(t, "8.15", list[0].String())
+}
+
+func TestSelectionEmpty(t *testing.T) {
+	_, err := Latest(nil)
+	assert.Error(t, err)
+	_, err = Oldest(nil)
+	assert.Error(t, err)
+	_, _, err = LatestAndOldest(nil)
+	assert.Error(t, err)
+}
+
+func TestLatestAndOldest(t *testing.T) {
+	latest, oldest, err := LatestAndOldest([]string{"8.10", "8.7", "8.19.11", "8.19"})
+	require.NoError(t, err)
+	assert.Equal(t, "8.19.11", latest.String())
+	assert.Equal(t, "8.7", oldest.String())
+
+	_, _, err = LatestAndOldest([]string{"8.10", "latest"})
+	assert.Error(t, err)
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"8", "8.19", "8.19.11", "9.1", "", "8.", "a.b", "8.19.11.1", "-1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		version, err := Parse(value)
+		if err != nil {
+			return
+		}
+		// a successfully parsed version must round trip and compare equal to itself
+		reparsed, err := Parse(version.String())
+		require.NoError(t, err)
+		assert.Equal(t, version, reparsed)
+		assert.Equal(t, 0, version.Compare(reparsed))
+		assert.True(t, version.Major >= 0 && version.Minor >= 0 && version.Patch >= 0)
+	})
+}